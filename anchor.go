@@ -43,10 +43,16 @@ type Anchor struct {
 	R []uint32
 	// N is the current length of W
 	N uint32
+
+	// v is incremented by each change to the working set
+	v uint64
 }
 
 // Create a new anchor with a given capacity and initial size.
 //
+// Buckets 0 through used-1 will be working. At least one bucket must be working, and the
+// capacity may not exceed 2^32−1 buckets; NewAnchor panics otherwise.
+//
 // 	INITANCHOR(a, w)
 // 	A[b] ← 0 for b = 0, 1, ..., a−1    ◃ |Wb| ← 0 for b ∈ A
 // 	R ← ∅                              ◃ Empty stack
@@ -55,6 +61,9 @@ type Anchor struct {
 // 	for b = a−1 downto w do            ◃ Remove initially unused buckets
 // 	  REMOVEBUCKET(b)
 func NewAnchor(buckets, used int) *Anchor {
+	if uint64(buckets) > 1<<32-1 || used < 1 || used > buckets {
+		panic("anchor: invalid capacity or size for an anchor")
+	}
 	a := &Anchor{
 		A: make([]uint32, buckets),
 		K: make([]uint32, buckets),
@@ -63,7 +72,7 @@ func NewAnchor(buckets, used int) *Anchor {
 		R: make([]uint32, buckets-used, buckets),
 		N: uint32(used),
 	}
-	for b := uint32(0); b < uint32(used); b++ {
+	for b := uint32(0); b < uint32(buckets); b++ {
 		a.K[b], a.W[b], a.L[b] = b, b, b
 	}
 	for b, r := uint32(buckets)-1, 0; b >= uint32(used); b, r = b-1, r+1 {
@@ -89,18 +98,7 @@ func NewAnchor(buckets, used int) *Anchor {
 // 	  b ← h
 // 	return b
 func (a *Anchor) GetBucket(key uint64) uint32 {
-	A, K := a.A, a.K
-	ha, hb, hc, hd := fleaInit(key)
-	b := fastMod(uint64(hd), uint64(len(A)))
-	for A[b] > 0 {
-		ha, hb, hc, hd = fleaRound(ha, hb, hc, hd)
-		h := fastMod(uint64(hd), uint64(A[b]))
-		for A[h] >= A[b] {
-			h = K[h]
-		}
-		b = h
-	}
-	return b
+	return getBucket(a.A, a.K, key)
 }
 
 // Get the path to the bucket which a hash-key is assigned to.
@@ -130,25 +128,14 @@ func (a *Anchor) GetBucket(key uint64) uint32 {
 // 	  b ← h
 // 	return P
 func (a *Anchor) GetPath(key uint64, pathBuffer []uint32) []uint32 {
-	A, K := a.A, a.K
-	ha, hb, hc, hd := fleaInit(key)
-	b := fastMod(uint64(hd), uint64(len(A)))
-	pathBuffer = append(pathBuffer, b)
-	for A[b] > 0 {
-		ha, hb, hc, hd = fleaRound(ha, hb, hc, hd)
-		h := fastMod(uint64(hd), uint64(A[b]))
-		pathBuffer = append(pathBuffer, h)
-		for A[h] >= A[b] {
-			h = K[h]
-			pathBuffer = append(pathBuffer, h)
-		}
-		b = h
-	}
-	return pathBuffer
+	return getPath(a.A, a.K, key, pathBuffer)
 }
 
 // Add a bucket to the anchor.
 //
+// The most recently removed bucket will be added. AddBucket panics if all buckets are
+// working.
+//
 // 	ADDBUCKET()
 // 	b ← R.pop()
 // 	A[b] ← 0       ◃ W ← W ∪ {b}, delete Wb
@@ -157,18 +144,20 @@ func (a *Anchor) GetPath(key uint64, pathBuffer []uint32) []uint32 {
 // 	N ← N + 1
 // 	return b
 func (a *Anchor) AddBucket() uint32 {
-	A, K, W, L, R, N := a.A, a.K, a.W, a.L, a.R, a.N
-	b := R[len(R)-1]
-	a.R = R[:len(R)-1]
-	A[b] = 0
-	L[W[N]] = N
-	W[L[b]], K[b] = b, b
+	if len(a.R) == 0 {
+		panic("anchor: no buckets to add")
+	}
+	b, R := addBucket(a.A, a.K, a.W, a.L, a.R, uint32(a.N))
+	a.R = R
 	a.N++
+	a.v++
 	return b
 }
 
 // Remove a bucket from the anchor.
 //
+// Removing a bucket which is not working, or the last working bucket, has no effect.
+//
 // 	REMOVEBUCKET(b)
 // 	R.push(b)
 // 	N ← N − 1
@@ -176,13 +165,17 @@ func (a *Anchor) AddBucket() uint32 {
 // 	W[L[b]] ← K[b] ← W[N]
 // 	L[W[N]] ← L[b]
 func (a *Anchor) RemoveBucket(b uint32) {
-	if a.A[b] != 0 {
+	if int(b) >= len(a.A) || a.A[b] != 0 || a.N == 1 {
 		return
 	}
 	a.N--
-	A, K, W, L, N := a.A, a.K, a.W, a.L, a.N
-	a.R = append(a.R, b)
-	A[b] = N
-	W[L[b]], K[b] = W[N], W[N]
-	L[W[N]] = L[b]
+	a.R = removeBucket(a.A, a.K, a.W, a.L, a.R, b, uint32(a.N))
+	a.v++
 }
+
+// Get the version of the anchor.
+//
+// The version is incremented by each call to AddBucket or RemoveBucket which changes the
+// working set, so callers may cheaply detect whether the assignment of keys to buckets may
+// have changed. Direct modifications to the exported fields of the anchor are not tracked.
+func (a *Anchor) Version() uint64 { return a.v }
@@ -23,6 +23,7 @@
 package anchor
 
 import (
+	"math/rand"
 	"testing"
 )
 
@@ -70,6 +71,48 @@ func BenchmarkGetBucket_5_10(b *testing.B) {
 	}
 }
 
+func BenchmarkGetBucketTiny_10_10(b *testing.B) {
+	const (
+		buckets = 10
+		used    = 10
+	)
+
+	a := NewTinyAnchor(buckets, used)
+	n := b.N
+	b.ResetTimer()
+	for i := 0; i < n; i++ {
+		_benchIgnore += uint32(a.GetBucket(uint64(i)))
+	}
+}
+
+func BenchmarkGetBucketTiny_9_10(b *testing.B) {
+	const (
+		buckets = 10
+		used    = 9
+	)
+
+	a := NewTinyAnchor(buckets, used)
+	n := b.N
+	b.ResetTimer()
+	for i := 0; i < n; i++ {
+		_benchIgnore += uint32(a.GetBucket(uint64(i)))
+	}
+}
+
+func BenchmarkGetBucketTiny_5_10(b *testing.B) {
+	const (
+		buckets = 10
+		used    = 5
+	)
+
+	a := NewTinyAnchor(buckets, used)
+	n := b.N
+	b.ResetTimer()
+	for i := 0; i < n; i++ {
+		_benchIgnore += uint32(a.GetBucket(uint64(i)))
+	}
+}
+
 func BenchmarkGetBucket_1m_1m(b *testing.B) {
 	const (
 		buckets = 1000000
@@ -111,3 +154,166 @@ func BenchmarkGetBucket_500k_1m(b *testing.B) {
 		_benchIgnore += a.GetBucket(uint64(i))
 	}
 }
+
+func benchmarkGetBuckets(b *testing.B, buckets, used int) {
+	benchmarkGetBucketsOf(b, NewAnchor(buckets, used))
+}
+
+func benchmarkGetBucketsOf(b *testing.B, a *Anchor) {
+	keys, out := make([]uint64, 1024), make([]uint32, 1024)
+	n := b.N
+	b.ResetTimer()
+	for i := 0; i < n; i += len(keys) {
+		for j := range keys {
+			keys[j] = uint64(i + j)
+		}
+		a.GetBuckets(keys, out)
+		_benchIgnore += out[0]
+	}
+}
+
+func benchmarkGetBucketLoop(b *testing.B, buckets, used int) {
+	benchmarkGetBucketLoopOf(b, NewAnchor(buckets, used))
+}
+
+func benchmarkGetBucketLoopOf(b *testing.B, a *Anchor) {
+	keys, out := make([]uint64, 1024), make([]uint32, 1024)
+	n := b.N
+	b.ResetTimer()
+	for i := 0; i < n; i += len(keys) {
+		for j := range keys {
+			keys[j] = uint64(i + j)
+		}
+		for j, key := range keys {
+			out[j] = a.GetBucket(key)
+		}
+		_benchIgnore += out[0]
+	}
+}
+
+func BenchmarkGetBuckets_1m_1m(b *testing.B)      { benchmarkGetBuckets(b, 1000000, 1000000) }
+func BenchmarkGetBucketLoop_1m_1m(b *testing.B)   { benchmarkGetBucketLoop(b, 1000000, 1000000) }
+func BenchmarkGetBuckets_900k_1m(b *testing.B)    { benchmarkGetBuckets(b, 1000000, 900000) }
+func BenchmarkGetBucketLoop_900k_1m(b *testing.B) { benchmarkGetBucketLoop(b, 1000000, 900000) }
+func BenchmarkGetBuckets_500k_1m(b *testing.B)    { benchmarkGetBuckets(b, 1000000, 500000) }
+func BenchmarkGetBucketLoop_500k_1m(b *testing.B) { benchmarkGetBucketLoop(b, 1000000, 500000) }
+
+func BenchmarkGetBuckets_random_500k_1m(b *testing.B) {
+	benchmarkGetBucketsOf(b, newRandomlyRemoved(1000000, 500000))
+}
+
+func BenchmarkGetBucketLoop_random_500k_1m(b *testing.B) {
+	benchmarkGetBucketLoopOf(b, newRandomlyRemoved(1000000, 500000))
+}
+
+func BenchmarkGetBucket_random_500k_1m(b *testing.B) {
+	a := newRandomlyRemoved(1000000, 500000)
+	n := b.N
+	b.ResetTimer()
+	for i := 0; i < n; i++ {
+		_benchIgnore += a.GetBucket(uint64(i))
+	}
+}
+
+// The packed layout stores A[b] and K[b] together for each bucket b, so searches through
+// removed buckets read A[h] and K[h] from the same cache line. Anchor and FrozenAnchor
+// store A and K in separate arrays, which was faster in every comparison between
+// BenchmarkGetBucketPacked_* and BenchmarkGetBucket_*, so the packed layout is only kept
+// here for measurement.
+type packedAnchor []entry32
+
+func newPackedAnchor(a *Anchor) packedAnchor {
+	p := make(packedAnchor, len(a.A))
+	for b := range p {
+		p[b] = entry32{a.A[b], a.K[b]}
+	}
+	return p
+}
+
+func (p packedAnchor) getBucket(key uint64) uint32 {
+	ha, hb, hc, hd := fleaInit(key)
+	b := fastMod(uint64(hd), uint64(len(p)))
+	for p[b].a > 0 {
+		ha, hb, hc, hd = fleaRound(ha, hb, hc, hd)
+		h := fastMod(uint64(hd), uint64(p[b].a))
+		for p[h].a >= p[b].a {
+			h = p[h].k
+		}
+		b = h
+	}
+	return b
+}
+
+func benchmarkGetBucketPacked(b *testing.B, a *Anchor) {
+	p := newPackedAnchor(a)
+	n := b.N
+	b.ResetTimer()
+	for i := 0; i < n; i++ {
+		_benchIgnore += p.getBucket(uint64(i))
+	}
+}
+
+func BenchmarkGetBucketPacked_1m_1m(b *testing.B) {
+	benchmarkGetBucketPacked(b, NewAnchor(1000000, 1000000))
+}
+
+func BenchmarkGetBucketPacked_900k_1m(b *testing.B) {
+	benchmarkGetBucketPacked(b, NewAnchor(1000000, 900000))
+}
+
+func BenchmarkGetBucketPacked_500k_1m(b *testing.B) {
+	benchmarkGetBucketPacked(b, NewAnchor(1000000, 500000))
+}
+
+func BenchmarkGetBucketPacked_random_500k_1m(b *testing.B) {
+	benchmarkGetBucketPacked(b, newRandomlyRemoved(1000000, 500000))
+}
+
+// Create an anchor with buckets removed in a random order, so searches for working
+// buckets will follow longer chains of successors in K.
+func newRandomlyRemoved(buckets, used int) *Anchor {
+	a := NewAnchor(buckets, buckets)
+	for _, b := range rand.New(rand.NewSource(1)).Perm(buckets)[:buckets-used] {
+		a.RemoveBucket(uint32(b))
+	}
+	return a
+}
+
+func BenchmarkGetBucketTable_5_10(b *testing.B) {
+	const (
+		buckets = 10
+		used    = 5
+	)
+
+	a := NewTableAnchor(buckets, used)
+	n := b.N
+	b.ResetTimer()
+	for i := 0; i < n; i++ {
+		_benchIgnore += uint32(a.GetBucket(uint64(i)))
+	}
+}
+
+func BenchmarkGetBucket_random_20_60(b *testing.B) {
+	a := NewCompactAnchor(60, 60)
+	for _, r := range rand.New(rand.NewSource(1)).Perm(60)[:40] {
+		a.RemoveBucket(uint16(r))
+	}
+	n := b.N
+	b.ResetTimer()
+	for i := 0; i < n; i++ {
+		_benchIgnore += uint32(a.GetBucket(uint64(i)))
+	}
+}
+
+func BenchmarkGetBucketTable_random_20_60(b *testing.B) {
+	a := NewCompactAnchor(60, 60)
+	for _, r := range rand.New(rand.NewSource(1)).Perm(60)[:40] {
+		a.RemoveBucket(uint16(r))
+	}
+	t := a.Table()
+	n := b.N
+	b.ResetTimer()
+	for i := 0; i < n; i++ {
+		_benchIgnore += uint32(t.GetBucket(uint64(i)))
+	}
+}
@@ -93,62 +93,80 @@ func TestPaths(t *testing.T) {
 	t.Logf("1 + ln(a/w) = %v\n", float64(1)+math.Log(float64(buckets)/float64(used)))
 }
 
+// Assign keys 0 through 1e6-1 to buckets, and check that each key is assigned to a working
+// bucket and each working bucket is assigned its share of keys to within 5 standard
+// deviations.
+func checkDistribution(t *testing.T, a *Anchor) []uint32 {
+	t.Helper()
+	const keys = 1e6
+	assigned := make([]uint32, keys)
+	counts := make([]int, a.Capacity())
+	for k := range assigned {
+		b := a.GetBucket(uint64(k))
+		if !a.IsWorking(b) {
+			t.Fatalf("key %v assigned to bucket %v, which is not a working bucket", k, b)
+		}
+		assigned[k] = b
+		counts[b]++
+	}
+	p := 1 / float64(a.Len())
+	expected, sigma := keys*p, math.Sqrt(keys*p*(1-p))
+	for b, count := range counts {
+		if a.IsWorking(uint32(b)) && math.Abs(float64(count)-expected) > 5*sigma {
+			t.Fatalf("bucket %v assigned %v keys, expected %.0f ± %.0f", b, count, expected, 5*sigma)
+		}
+	}
+	return assigned
+}
+
+// Check that keys only moved to or from bucket b between two assignments.
+func checkMoved(t *testing.T, before, after []uint32, b uint32) {
+	t.Helper()
+	for k := range before {
+		if before[k] != after[k] && before[k] != b && after[k] != b {
+			t.Fatalf("key %v moved from bucket %v to %v, expected only moves to or from %v", k, before[k], after[k], b)
+		}
+	}
+}
+
+// Check that bucket additions return the expected buckets.
+func checkAdded(t *testing.T, a *Anchor, expected ...uint32) {
+	t.Helper()
+	for _, e := range expected {
+		if b := a.AddBucket(); b != e {
+			t.Fatalf("added bucket %v, expected %v", b, e)
+		}
+	}
+}
+
 func TestOrdering(t *testing.T) {
 	const (
 		buckets = 5
 		used    = 5
 	)
 	a := NewAnchor(buckets, used)
-
-	counts := make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
-	}
-	t.Logf("%#+v\n", counts)
+	initial := checkDistribution(t, a)
 
 	a.RemoveBucket(4)
 	a.RemoveBucket(3)
 	a.RemoveBucket(2)
-	t.Logf("removed b=4,3,2\n")
+	checkDistribution(t, a)
 
-	counts = make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
+	// Buckets are added in the reverse order of their removal, which restores every key
+	checkAdded(t, a, 2, 3, 4)
+	if !reflect.DeepEqual(checkDistribution(t, a), initial) {
+		t.Fatalf("keys moved after removing and adding b=4,3,2")
 	}
-	t.Logf("%#+v\n", counts)
-
-	a.AddBucket()
-	a.AddBucket()
-	a.AddBucket()
-	t.Logf("added b=2,3,4\n")
-
-	counts = make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
-	}
-	t.Logf("%#+v\n", counts)
 
 	a.RemoveBucket(2)
 	a.RemoveBucket(3)
 	a.RemoveBucket(4)
-	t.Logf("removed b=2,3,4\n")
-
-	counts = make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
-	}
-	t.Logf("%#+v\n", counts)
-
-	a.AddBucket()
-	a.AddBucket()
-	a.AddBucket()
-	t.Logf("added b=4,3,2\n")
+	checkDistribution(t, a)
 
-	counts = make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
+	checkAdded(t, a, 4, 3, 2)
+	if !reflect.DeepEqual(checkDistribution(t, a), initial) {
+		t.Fatalf("keys moved after removing and adding b=2,3,4")
 	}
-	t.Logf("%#+v\n", counts)
 }
 
 func TestDistributionSimple(t *testing.T) {
@@ -157,36 +175,19 @@ func TestDistributionSimple(t *testing.T) {
 		used    = 5
 	)
 	a := NewAnchor(buckets, used)
+	initial := checkDistribution(t, a)
 
-	counts := make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
-	}
-	t.Logf("%#+v\n", counts)
-
-	a.AddBucket()
-	a.AddBucket()
-	a.AddBucket()
-	a.AddBucket()
-	a.AddBucket()
-
-	counts = make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
-	}
-	t.Logf("%#+v\n", counts)
+	checkAdded(t, a, 5, 6, 7, 8, 9)
+	checkDistribution(t, a)
 
 	a.RemoveBucket(9)
 	a.RemoveBucket(8)
 	a.RemoveBucket(7)
 	a.RemoveBucket(6)
 	a.RemoveBucket(5)
-
-	counts = make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
+	if !reflect.DeepEqual(checkDistribution(t, a), initial) {
+		t.Fatalf("keys moved after adding and removing b=5,6,7,8,9")
 	}
-	t.Logf("%#+v\n", counts)
 }
 
 func TestDistributionExtended(t *testing.T) {
@@ -195,58 +196,59 @@ func TestDistributionExtended(t *testing.T) {
 		used    = 10
 	)
 	a := NewAnchor(buckets, used)
+	states := [][]uint32{checkDistribution(t, a)}
 
-	counts := make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
+	// Each removal only moves keys from the removed bucket
+	for _, b := range []uint32{9, 5, 3} {
+		a.RemoveBucket(b)
+		states = append(states, checkDistribution(t, a))
+		checkMoved(t, states[len(states)-2], states[len(states)-1], b)
 	}
-	t.Logf("%#+v\n", counts)
 
-	a.RemoveBucket(9)
-	t.Logf("removed b=9\n")
-	counts = make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
+	// Each addition only moves keys to the added bucket, and restores the assignment of
+	// every key from before the bucket was removed
+	for i, b := range []uint32{3, 5, 9} {
+		checkAdded(t, a, b)
+		after := checkDistribution(t, a)
+		checkMoved(t, states[len(states)-1-i], after, b)
+		if !reflect.DeepEqual(after, states[len(states)-2-i]) {
+			t.Fatalf("keys moved after removing and adding b=%v", b)
+		}
 	}
-	t.Logf("%#+v\n", counts)
+}
 
-	a.RemoveBucket(5)
-	t.Logf("removed b=5\n")
-	counts = make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
+func TestVersion(t *testing.T) {
+	a := NewAnchor(10, 5)
+	if v := a.Version(); v != 0 {
+		t.Fatalf("initial version = %v", v)
 	}
-	t.Logf("%#+v\n", counts)
-
 	a.RemoveBucket(3)
-	t.Logf("removed b=3\n")
-	counts = make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
-	}
-	t.Logf("%#+v\n", counts)
-
+	a.RemoveBucket(3) // already removed
+	a.RemoveBucket(7) // never added
 	a.AddBucket()
-	t.Logf("re-added b=3\n")
-	counts = make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
+	if v := a.Version(); v != 2 {
+		t.Fatalf("version = %v, expected 2", v)
 	}
-	t.Logf("%#+v\n", counts)
-
-	a.AddBucket()
-	t.Logf("re-added b=5\n")
-	counts = make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
+	if v := a.Freeze().Version(); v != 2 {
+		t.Fatalf("frozen version = %v, expected 2", v)
 	}
-	t.Logf("%#+v\n", counts)
+}
 
-	a.AddBucket()
-	t.Logf("re-added b=9\n")
-	counts = make([]int, buckets)
-	for i := uint64(0); i < 1e6; i++ {
-		counts[a.GetBucket(i)]++
+func TestAnchorInvalidSize(t *testing.T) {
+	for _, size := range [][2]int{{10, 0}, {10, 11}, {0, 0}, {-1, 1}} {
+		for name, create := range map[string]func(buckets, used int){
+			"anchor":     func(buckets, used int) { NewAnchor(buckets, used) },
+			"hash":       func(buckets, used int) { New(buckets, used) },
+			"concurrent": func(buckets, used int) { NewConcurrentAnchor(buckets, used) },
+		} {
+			func() {
+				defer func() {
+					if r := recover(); r != "anchor: invalid capacity or size for an anchor" {
+						t.Fatalf("%v: capacity %v with %v working buckets: recovered %v", name, size[0], size[1], r)
+					}
+				}()
+				create(size[0], size[1])
+			}()
+		}
 	}
-	t.Logf("%#+v\n", counts)
 }
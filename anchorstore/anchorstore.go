@@ -0,0 +1,496 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// package anchorstore persists an anchor in a local directory, so it survives restarts.
+//
+// A store holds a snapshot of the anchor and a write-ahead log (WAL) of the changes made
+// since the snapshot was taken. Each change is appended to the log before it is
+// acknowledged, and the log is replayed over the snapshot when the store is opened.
+// Every Options.SnapshotEvery changes, a new snapshot is written and the log is
+// truncated, so the log never grows without bound.
+//
+// The directory holds two files:
+//
+//   - snapshot: a CRC-32C checksum followed by the binary encoding of the anchor. New
+//     snapshots are written to a temporary file which replaces the old snapshot by
+//     renaming it, so a crash never leaves a partial snapshot.
+//   - wal: a sequence of records, each holding a batch of changes. Each record begins
+//     with the length and CRC-32C checksum of its payload. The payload holds the version
+//     of the anchor before the batch, the number of changes, and the kind and bucket of
+//     each change.
+//
+// A crash while appending to the log may leave a torn final record, or a zero-filled tail
+// where the file was extended but its contents never reached stable storage. Either is
+// discarded when the store is opened, as long as no complete record follows it. Any other
+// damage to the log or snapshot is reported as ErrCorrupt.
+package anchorstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/wdamron/go-anchorhash"
+)
+
+var (
+	// ErrCorrupt is returned when opening a store whose snapshot or log is damaged, other
+	// than by a torn final log record or a zero-filled tail.
+	ErrCorrupt = errors.New("anchorstore: corrupt snapshot or log")
+	// ErrClosed is returned when changing a store after it has been closed.
+	ErrClosed = errors.New("anchorstore: store closed")
+)
+
+const (
+	snapshotName = "snapshot"
+	walName      = "wal"
+	// Length and checksum which precede the payload of each log record.
+	recordHeaderSize = 8
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// SyncPolicy determines when changes appended to the log are flushed to stable storage.
+type SyncPolicy uint8
+
+const (
+	// SyncAlways flushes the log after each change, before the change is acknowledged.
+	// Acknowledged changes are never lost.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval flushes the log with the first change made at least
+	// Options.SyncInterval after the last flush. Changes made since the last flush may be
+	// lost if the machine crashes.
+	SyncInterval
+	// SyncNever leaves flushing the log to the operating system, or to Store.Sync and
+	// Store.Close. Changes made since the last flush may be lost if the machine crashes.
+	SyncNever
+)
+
+// Options configures a store. Zero values select the defaults.
+type Options struct {
+	// Sync determines when changes are flushed to stable storage. Defaults to SyncAlways.
+	Sync SyncPolicy
+	// SyncInterval is the minimum time between flushes for SyncInterval. Defaults to
+	// one second.
+	SyncInterval time.Duration
+	// SnapshotEvery is the number of changes after which a new snapshot is written and
+	// the log is truncated. Defaults to 10,000. Snapshots will only be written by
+	// Store.Compact if SnapshotEvery is negative.
+	SnapshotEvery int
+}
+
+// Store persists an anchor as a snapshot and a write-ahead log of changes.
+//
+// A store is not safe for concurrent use. Only one store may be open for a directory at
+// a time.
+type Store struct {
+	dir  string
+	opts Options
+	h    *anchor.Hash
+	wal  *os.File
+	// changes is the number of changes appended to the log since the last snapshot.
+	changes  int
+	lastSync time.Time
+	// err is the first error encountered while writing, after which the store refuses
+	// further changes, since the log may end with a partial or unflushed record.
+	err error
+}
+
+// Open the store in a directory, creating the directory and a new anchor with the given
+// capacity and initial size if the directory holds no snapshot.
+//
+// The anchor is recovered from the snapshot and log if they exist, in which case buckets
+// and used are ignored. A torn final log record or zero-filled tail is discarded. If a new
+// anchor must be created, anchor.ErrInvalidWorkingSet will be returned unless
+// 1 ≤ used ≤ buckets.
+func Open(dir string, buckets, used int, opts Options) (*Store, error) {
+	if opts.SyncInterval == 0 {
+		opts.SyncInterval = time.Second
+	}
+	if opts.SnapshotEvery == 0 {
+		opts.SnapshotEvery = 10000
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir, opts: opts, lastSync: time.Now()}
+	h, err := s.readSnapshot()
+	if errors.Is(err, os.ErrNotExist) {
+		if buckets <= 0 || uint64(buckets) > 1<<32-1 || used <= 0 || used > buckets {
+			return nil, anchor.ErrInvalidWorkingSet
+		}
+		h = anchor.New(buckets, used)
+		err = s.writeSnapshot(h)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.h = h
+	if s.wal, err = os.OpenFile(filepath.Join(dir, walName), os.O_RDWR|os.O_CREATE, 0o644); err != nil {
+		return nil, err
+	}
+	// The log may have just been created, and records flushed to it would be lost along
+	// with it unless its directory entry is durable
+	if err = syncDir(dir); err != nil {
+		s.wal.Close()
+		return nil, err
+	}
+	if err = s.replay(); err != nil {
+		s.wal.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get the bucket which a hash-key is assigned to.
+//
+// See anchor.Anchor.GetBucket for more information.
+func (s *Store) GetBucket(key uint64) uint32 { return s.h.GetBucket(key) }
+
+// Get the version of the anchor.
+func (s *Store) Version() uint64 { return s.h.Version() }
+
+// Create an immutable, lookup-only copy of the anchor.
+func (s *Store) Freeze() *anchor.FrozenAnchor { return s.h.Freeze() }
+
+// Add a bucket to the anchor and return it, once the change has been logged. An error
+// wrapping anchor.ErrInvalidOp will be returned if all buckets are working.
+//
+// See anchor.Anchor.AddBucket for more information.
+func (s *Store) AddBucket() (uint32, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	R := s.h.R()
+	if R.Len() == 0 {
+		return 0, fmt.Errorf("%w: no buckets to add", anchor.ErrInvalidOp)
+	}
+	b := R.At(R.Len() - 1)
+	return b, s.change([]anchor.Op{{Kind: anchor.OpAdd, Bucket: b}}, func() { s.h.AddBucket() })
+}
+
+// Remove a bucket from the anchor, once the change has been logged. Removals which have
+// no effect are not logged.
+//
+// See anchor.Anchor.RemoveBucket for more information.
+func (s *Store) RemoveBucket(b uint32) error {
+	if s.err != nil {
+		return s.err
+	}
+	if !s.h.IsWorking(b) || s.h.Len() == 1 {
+		return nil
+	}
+	return s.change([]anchor.Op{{Kind: anchor.OpRemove, Bucket: b}}, func() { s.h.RemoveBucket(b) })
+}
+
+// Apply a batch of operations to the anchor, or none of them, once the batch has been
+// logged. The batch is logged as a single record, so it is recovered entirely or not
+// at all.
+//
+// See anchor.Anchor.ApplyBatch for more information.
+func (s *Store) ApplyBatch(ops []anchor.Op) error {
+	if s.err != nil {
+		return s.err
+	}
+	// Validate the batch against a copy, so that an invalid batch is never logged
+	if err := s.h.Anchor().ApplyBatch(ops); err != nil || len(ops) == 0 {
+		return err
+	}
+	return s.change(ops, func() { s.h.ApplyBatch(ops) })
+}
+
+// Flush the log to stable storage.
+func (s *Store) Sync() error {
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.wal.Sync(); err != nil {
+		return s.fail(err)
+	}
+	s.lastSync = time.Now()
+	return nil
+}
+
+// Write a new snapshot of the anchor and truncate the log.
+func (s *Store) Compact() error {
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.writeSnapshot(s.h); err != nil {
+		return s.fail(err)
+	}
+	// A crash before the log is truncated leaves records which are already included in
+	// the snapshot; replay skips them by their versions.
+	if err := s.wal.Truncate(0); err != nil {
+		return s.fail(err)
+	}
+	if _, err := s.wal.Seek(0, io.SeekStart); err != nil {
+		return s.fail(err)
+	}
+	s.changes = 0
+	return s.Sync()
+}
+
+// Flush the log and close the store.
+func (s *Store) Close() error {
+	if s.err == ErrClosed {
+		return ErrClosed
+	}
+	err := s.err
+	if err == nil {
+		err = s.wal.Sync()
+	}
+	if cerr := s.wal.Close(); err == nil {
+		err = cerr
+	}
+	s.err = ErrClosed
+	return err
+}
+
+// Record the first error encountered while writing.
+func (s *Store) fail(err error) error {
+	if s.err == nil {
+		s.err = err
+	}
+	return err
+}
+
+// Log a valid batch of operations, then apply it to the anchor. The anchor is not changed
+// if the batch could not be logged, so lookups never reflect changes which would be lost
+// on recovery.
+func (s *Store) change(ops []anchor.Op, apply func()) error {
+	if err := s.log(s.h.Version(), ops); err != nil {
+		return err
+	}
+	apply()
+	if s.changes += len(ops); s.opts.SnapshotEvery > 0 && s.changes >= s.opts.SnapshotEvery {
+		return s.Compact()
+	}
+	return nil
+}
+
+// Append a batch of operations, to be applied to the anchor at version v, to the log and
+// flush it according to the sync policy.
+func (s *Store) log(v uint64, ops []anchor.Op) error {
+	payload := binary.AppendUvarint(nil, v)
+	payload = binary.AppendUvarint(payload, uint64(len(ops)))
+	for _, op := range ops {
+		payload = append(payload, byte(op.Kind))
+		payload = binary.AppendUvarint(payload, uint64(op.Bucket))
+	}
+	record := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(record, uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:], crc32.Checksum(payload, crcTable))
+	if _, err := s.wal.Write(append(record, payload...)); err != nil {
+		return s.fail(err)
+	}
+	switch s.opts.Sync {
+	case SyncAlways:
+		return s.Sync()
+	case SyncInterval:
+		if time.Since(s.lastSync) >= s.opts.SyncInterval {
+			return s.Sync()
+		}
+	}
+	return nil
+}
+
+// Replay the log over the anchor recovered from the snapshot, truncating a torn final
+// record or zero-filled tail.
+func (s *Store) replay() error {
+	data, err := io.ReadAll(s.wal)
+	if err != nil {
+		return err
+	}
+	offset := 0
+	for offset < len(data) {
+		payload, ok := record(data[offset:])
+		if !ok {
+			if !torn(data[offset:]) {
+				return ErrCorrupt
+			}
+			break
+		}
+		if err := s.apply(payload); err != nil {
+			return err
+		}
+		offset += recordHeaderSize + len(payload)
+	}
+	if offset < len(data) {
+		if err := s.wal.Truncate(int64(offset)); err != nil {
+			return err
+		}
+		if err := s.wal.Sync(); err != nil {
+			return err
+		}
+	}
+	_, err = s.wal.Seek(int64(offset), io.SeekStart)
+	return err
+}
+
+// Get the payload of the complete log record at the start of data, if its checksum is
+// valid. Every record written by the store has a non-empty payload, so a zero-filled
+// header (with the checksum of an empty payload) is not a record.
+func record(data []byte) ([]byte, bool) {
+	if len(data) < recordHeaderSize {
+		return nil, false
+	}
+	n := uint64(binary.LittleEndian.Uint32(data))
+	if n == 0 || n > uint64(len(data)-recordHeaderSize) {
+		return nil, false
+	}
+	payload := data[recordHeaderSize : recordHeaderSize+int(n)]
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(data[4:]) {
+		return nil, false
+	}
+	return payload, true
+}
+
+// Check if the tail of the log following the last complete record could have been left by
+// a crash while appending: either it is zero-filled, or it is a single record which is
+// shorter than its header or declared length, or which ends at the end of the log. A
+// damaged length may hide later records, so no complete record may begin within the tail.
+func torn(tail []byte) bool {
+	zero := true
+	for _, c := range tail {
+		if c != 0 {
+			zero = false
+			break
+		}
+	}
+	if zero {
+		return true
+	}
+	if len(tail) >= recordHeaderSize {
+		n := uint64(binary.LittleEndian.Uint32(tail))
+		if recordHeaderSize+n < uint64(len(tail)) {
+			return false // more than one record
+		}
+	}
+	for i := 1; i < len(tail); i++ {
+		if _, ok := record(tail[i:]); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply a batch of operations decoded from a log record, unless the snapshot already
+// includes it.
+func (s *Store) apply(payload []byte) error {
+	v, n := binary.Uvarint(payload)
+	if n <= 0 {
+		return ErrCorrupt
+	}
+	payload = payload[n:]
+	count, n := binary.Uvarint(payload)
+	if n <= 0 || count > uint64(len(payload)) {
+		return ErrCorrupt
+	}
+	payload = payload[n:]
+	ops := make([]anchor.Op, count)
+	for i := range ops {
+		if len(payload) == 0 {
+			return ErrCorrupt
+		}
+		kind := anchor.OpKind(payload[0])
+		b, n := binary.Uvarint(payload[1:])
+		if n <= 0 || b > 1<<32-1 {
+			return ErrCorrupt
+		}
+		ops[i], payload = anchor.Op{Kind: kind, Bucket: uint32(b)}, payload[1+n:]
+	}
+	switch current := s.h.Version(); {
+	case len(payload) != 0:
+		return ErrCorrupt
+	case v+count <= current:
+		return nil // included in the snapshot
+	case v != current:
+		return ErrCorrupt
+	}
+	if err := s.h.ApplyBatch(ops); err != nil {
+		return ErrCorrupt
+	}
+	s.changes += len(ops)
+	return nil
+}
+
+func (s *Store) readSnapshot() (*anchor.Hash, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, snapshotName))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 || crc32.Checksum(data[4:], crcTable) != binary.LittleEndian.Uint32(data) {
+		return nil, ErrCorrupt
+	}
+	h := new(anchor.Hash)
+	if err := h.UnmarshalBinary(data[4:]); err != nil {
+		return nil, ErrCorrupt
+	}
+	return h, nil
+}
+
+// Replace the snapshot atomically by writing a temporary file and renaming it.
+func (s *Store) writeSnapshot(h *anchor.Hash) error {
+	payload, err := h.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	data := binary.LittleEndian.AppendUint32(make([]byte, 0, 4+len(payload)), crc32.Checksum(payload, crcTable))
+	data = append(data, payload...)
+	tmp := filepath.Join(s.dir, snapshotName+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, filepath.Join(s.dir, snapshotName))
+	}
+	if err != nil {
+		return err
+	}
+	return syncDir(s.dir)
+}
+
+// Flush a directory, so that files created or renamed within it are durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,268 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchorstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/wdamron/go-anchorhash"
+)
+
+func open(t *testing.T, dir string, opts Options) *Store {
+	t.Helper()
+	s, err := Open(dir, 100, 80, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func walSize(t *testing.T, dir string) int64 {
+	t.Helper()
+	info, err := os.Stat(filepath.Join(dir, walName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestRecover(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir, Options{})
+	for _, b := range []uint32{3, 17, 42} {
+		if err := s.RemoveBucket(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b, err := s.AddBucket(); err != nil || b != 42 {
+		t.Fatalf("added %v (err = %v), expected 42", b, err)
+	}
+	if err := s.ApplyBatch([]anchor.Op{{Kind: anchor.OpAdd, Bucket: 17}, {Kind: anchor.OpRemove, Bucket: 5}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ApplyBatch([]anchor.Op{{Kind: anchor.OpRemove, Bucket: 5}}); !errors.Is(err, anchor.ErrInvalidOp) {
+		t.Fatalf("err = %v, expected %v", err, anchor.ErrInvalidOp)
+	}
+	expected := s.Freeze()
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveBucket(1); err != ErrClosed {
+		t.Fatalf("err = %v, expected %v", err, ErrClosed)
+	}
+
+	s = open(t, dir, Options{})
+	defer s.Close()
+	if got := s.Freeze(); !reflect.DeepEqual(got, expected) || s.Version() != 6 {
+		t.Fatalf("recovered version %v differs from version %v", s.Version(), expected.Version())
+	}
+}
+
+func TestInvalidChanges(t *testing.T) {
+	for _, size := range [][2]int{{0, 0}, {10, 0}, {10, 11}} {
+		if _, err := Open(t.TempDir(), size[0], size[1], Options{}); err != anchor.ErrInvalidWorkingSet {
+			t.Fatalf("Open with capacity %v and %v working buckets: err = %v", size[0], size[1], err)
+		}
+	}
+
+	dir := t.TempDir()
+	open(t, dir, Options{}).Close()
+	s, err := Open(dir, 0, 0, Options{})
+	if err != nil {
+		t.Fatalf("capacity was not ignored for an existing store: %v", err)
+	}
+	for b := 0; b < 20; b++ {
+		if _, err := s.AddBucket(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.AddBucket(); !errors.Is(err, anchor.ErrInvalidOp) {
+		t.Fatalf("err = %v, expected %v", err, anchor.ErrInvalidOp)
+	}
+	if err := s.RemoveBucket(3); err != nil {
+		t.Fatalf("store refused changes after an invalid change: %v", err)
+	}
+	s.Close()
+}
+
+func TestFailedWrite(t *testing.T) {
+	s := open(t, t.TempDir(), Options{})
+	s.RemoveBucket(3)
+	expected := s.Freeze()
+
+	// Changes which could not be logged must not be visible to lookups
+	s.wal.Close()
+	if err := s.RemoveBucket(4); err == nil {
+		t.Fatal("removed a bucket without logging it")
+	}
+	if err := s.ApplyBatch([]anchor.Op{{Kind: anchor.OpRemove, Bucket: 5}}); err == nil {
+		t.Fatal("applied a batch without logging it")
+	}
+	if _, err := s.AddBucket(); err == nil {
+		t.Fatal("added a bucket without logging it")
+	}
+	if got := s.Freeze(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("version = %v after failed writes, expected %v", got.Version(), expected.Version())
+	}
+}
+
+func TestTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir, Options{Sync: SyncNever})
+	s.RemoveBucket(3)
+	expected := s.Freeze()
+	size := walSize(t, dir)
+	s.RemoveBucket(4)
+	s.Close()
+
+	// Tear the final record at every possible length
+	wal := filepath.Join(dir, walName)
+	data, _ := os.ReadFile(wal)
+	for n := size; n < int64(len(data)); n++ {
+		if err := os.WriteFile(wal, data[:n], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		s := open(t, dir, Options{})
+		if got := s.Freeze(); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("torn at %v bytes: recovered version %v, expected %v", n, got.Version(), expected.Version())
+		}
+		if walSize(t, dir) != size {
+			t.Fatalf("torn at %v bytes: log was not truncated", n)
+		}
+		s.Close()
+	}
+
+	// A damaged final record is also discarded, and later changes are appended after it
+	damaged := append([]byte(nil), data...)
+	damaged[len(damaged)-1] ^= 0xFF
+	os.WriteFile(wal, damaged, 0o644)
+	s = open(t, dir, Options{})
+	if s.Version() != 1 {
+		t.Fatalf("version = %v, expected 1", s.Version())
+	}
+	s.RemoveBucket(9)
+	s.Close()
+	s = open(t, dir, Options{})
+	defer s.Close()
+	if s.Version() != 2 || s.Freeze().IsWorking(9) {
+		t.Fatalf("change after a torn record was lost")
+	}
+	s.Close()
+
+	// A zero-filled tail is discarded, even though a zero header has the checksum of an
+	// empty payload
+	data, _ = os.ReadFile(wal)
+	os.WriteFile(wal, append(append([]byte(nil), data...), make([]byte, 2*recordHeaderSize)...), 0o644)
+	s = open(t, dir, Options{})
+	if s.Version() != 2 {
+		t.Fatalf("zero-filled tail: version = %v, expected 2", s.Version())
+	}
+	if walSize(t, dir) != int64(len(data)) {
+		t.Fatalf("zero-filled tail: log was not truncated")
+	}
+}
+
+func TestCorruptLog(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir, Options{})
+	s.RemoveBucket(3)
+	s.RemoveBucket(4)
+	s.Close()
+
+	wal := filepath.Join(dir, walName)
+	data, _ := os.ReadFile(wal)
+	data[recordHeaderSize] ^= 0xFF
+	os.WriteFile(wal, data, 0o644)
+	if _, err := Open(dir, 100, 80, Options{}); err != ErrCorrupt {
+		t.Fatalf("err = %v, expected %v", err, ErrCorrupt)
+	}
+	data[recordHeaderSize] ^= 0xFF
+	os.WriteFile(wal, data, 0o644)
+
+	// A damaged length in the first of three records must not truncate the later records,
+	// whether it claims to extend past the end of the log or to end early
+	s = open(t, dir, Options{})
+	s.RemoveBucket(5)
+	s.Close()
+	data, _ = os.ReadFile(wal)
+	for _, length := range []uint32{1 << 20, 1} {
+		damaged := append([]byte(nil), data...)
+		binary.LittleEndian.PutUint32(damaged, length)
+		os.WriteFile(wal, damaged, 0o644)
+		if _, err := Open(dir, 100, 80, Options{}); err != ErrCorrupt {
+			t.Fatalf("length %v: err = %v, expected %v", length, err, ErrCorrupt)
+		}
+		if walSize(t, dir) != int64(len(data)) {
+			t.Fatalf("length %v: log was truncated", length)
+		}
+	}
+
+	snapshot := filepath.Join(dir, snapshotName)
+	data, _ = os.ReadFile(snapshot)
+	data[len(data)-1] ^= 0xFF
+	os.WriteFile(snapshot, data, 0o644)
+	if _, err := Open(dir, 100, 80, Options{}); err != ErrCorrupt {
+		t.Fatalf("err = %v, expected %v", err, ErrCorrupt)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir, Options{SnapshotEvery: 5})
+	for b := uint32(0); b < 12; b++ {
+		if err := s.RemoveBucket(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if size := walSize(t, dir); size == 0 || size > 2*(recordHeaderSize+8) {
+		t.Fatalf("log holds %v bytes, expected 2 records", size)
+	}
+
+	// A crash between writing a snapshot and truncating the log leaves records which the
+	// snapshot already includes
+	wal := filepath.Join(dir, walName)
+	stale, _ := os.ReadFile(wal)
+	for b := uint32(12); b < 15; b++ {
+		s.RemoveBucket(b)
+	}
+	expected := s.Freeze()
+	s.Close()
+	if walSize(t, dir) != 0 {
+		t.Fatalf("log was not truncated by compaction")
+	}
+	os.WriteFile(wal, stale, 0o644)
+
+	s = open(t, dir, Options{SnapshotEvery: 5})
+	defer s.Close()
+	if got := s.Freeze(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("recovered version %v, expected %v", got.Version(), expected.Version())
+	}
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+}
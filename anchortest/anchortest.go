@@ -0,0 +1,204 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// package anchortest checks the properties of consistent-hash implementations.
+//
+// A checker drives random sequences of bucket additions and removals through an
+// implementation of anchor.ConsistentHash, and asserts after each change that:
+//
+//   - Keys are only assigned to working buckets.
+//   - Minimal disruption: removing a bucket only moves the keys assigned to it.
+//   - Monotonicity: adding a bucket only moves keys to the added bucket.
+//   - Balance: keys are spread uniformly across working buckets, as measured by a
+//     chi-square test.
+//
+// Failures report the seed which produced the sequence of changes, so they may be
+// reproduced by setting Options.Seed.
+package anchortest
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/wdamron/go-anchorhash"
+)
+
+// Options configures a checker. Zero values select the defaults.
+type Options struct {
+	// Seed for the random sequence of keys and changes. If zero, a seed will be chosen
+	// from the current time and reported on failure.
+	Seed int64
+	// Number of random additions or removals. Defaults to 100.
+	Steps int
+	// Number of keys to track. Defaults to 10,000.
+	Keys int
+	// Maximum number of standard deviations by which the chi-square statistic for the
+	// distribution of keys may exceed its expected value. Defaults to 5. Balance will not
+	// be checked if BalanceSigma is negative.
+	BalanceSigma float64
+}
+
+// Check the properties of a consistent-hash implementation.
+//
+// newHash must return a hash in which buckets 0 through Len()-1 are working.
+func Check(t testing.TB, newHash func() anchor.ConsistentHash, opts Options) {
+	t.Helper()
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	if opts.Steps == 0 {
+		opts.Steps = 100
+	}
+	if opts.Keys == 0 {
+		opts.Keys = 10000
+	}
+	if opts.BalanceSigma == 0 {
+		opts.BalanceSigma = 5
+	}
+	c := &checker{t: t, opts: opts, h: newHash(), rng: rand.New(rand.NewSource(opts.Seed))}
+	c.run()
+}
+
+type checker struct {
+	t    testing.TB
+	opts Options
+	h    anchor.ConsistentHash
+	rng  *rand.Rand
+	// working[b] is true if b is a working bucket.
+	working []bool
+	keys    []uint64
+	// assigned[i] is the bucket which keys[i] is assigned to.
+	assigned []uint32
+}
+
+func (c *checker) fatalf(format string, args ...interface{}) {
+	c.t.Helper()
+	c.t.Fatalf("anchortest (seed %v): "+format, append([]interface{}{c.opts.Seed}, args...)...)
+}
+
+func (c *checker) run() {
+	c.t.Helper()
+	c.working = make([]bool, c.h.Capacity())
+	for b := 0; b < c.h.Len(); b++ {
+		c.working[b] = true
+	}
+	c.keys, c.assigned = make([]uint64, c.opts.Keys), make([]uint32, c.opts.Keys)
+	for i := range c.keys {
+		c.keys[i] = c.rng.Uint64()
+		c.assigned[i] = c.h.GetBucket(c.keys[i])
+	}
+	c.checkAssigned(0)
+	c.checkBalance(0)
+
+	for step := 1; step <= c.opts.Steps; step++ {
+		n := c.h.Len()
+		if n < len(c.working) && (n == 1 || c.rng.Intn(2) == 0) {
+			c.add(step)
+		} else {
+			c.remove(step)
+		}
+		c.checkAssigned(step)
+	}
+	c.checkBalance(c.opts.Steps)
+}
+
+func (c *checker) add(step int) {
+	c.t.Helper()
+	n := c.h.Len()
+	b := c.h.AddBucket()
+	if int(b) >= len(c.working) || c.working[b] {
+		c.fatalf("step %v: added bucket %v, which is not a removed bucket", step, b)
+	}
+	if c.h.Len() != n+1 {
+		c.fatalf("step %v: added bucket %v, len = %v, expected %v", step, b, c.h.Len(), n+1)
+	}
+	c.working[b] = true
+	for i, key := range c.keys {
+		next := c.h.GetBucket(key)
+		if next != c.assigned[i] && next != b {
+			c.fatalf("step %v: added bucket %v, key %v moved from bucket %v to %v", step, b, key, c.assigned[i], next)
+		}
+		c.assigned[i] = next
+	}
+}
+
+func (c *checker) remove(step int) {
+	c.t.Helper()
+	working := make([]uint32, 0, len(c.working))
+	for b, w := range c.working {
+		if w {
+			working = append(working, uint32(b))
+		}
+	}
+	n := c.h.Len()
+	b := working[c.rng.Intn(len(working))]
+	c.h.RemoveBucket(b)
+	// Some algorithms may refuse to remove certain buckets
+	removed := c.h.Len() == n-1
+	if !removed && c.h.Len() != n {
+		c.fatalf("step %v: removed bucket %v, len = %v, expected %v or %v", step, b, c.h.Len(), n-1, n)
+	}
+	c.working[b] = !removed
+	for i, key := range c.keys {
+		next := c.h.GetBucket(key)
+		if next != c.assigned[i] && (!removed || c.assigned[i] != b) {
+			c.fatalf("step %v: removed bucket %v, key %v moved from bucket %v to %v", step, b, key, c.assigned[i], next)
+		}
+		c.assigned[i] = next
+	}
+}
+
+func (c *checker) checkAssigned(step int) {
+	c.t.Helper()
+	for i, b := range c.assigned {
+		if int(b) >= len(c.working) || !c.working[b] {
+			c.fatalf("step %v: key %v assigned to bucket %v, which is not a working bucket", step, c.keys[i], b)
+		}
+	}
+}
+
+func (c *checker) checkBalance(step int) {
+	c.t.Helper()
+	n := c.h.Len()
+	if c.opts.BalanceSigma < 0 || n < 2 {
+		return
+	}
+	counts := make([]int, len(c.working))
+	for _, b := range c.assigned {
+		counts[b]++
+	}
+	expected := float64(len(c.keys)) / float64(n)
+	chiSquare := 0.0
+	for b, count := range counts {
+		if c.working[b] {
+			d := float64(count) - expected
+			chiSquare += d * d / expected
+		}
+	}
+	// The chi-square statistic with n-1 degrees of freedom has mean n-1 and variance 2(n-1)
+	df := float64(n - 1)
+	if limit := df + c.opts.BalanceSigma*math.Sqrt(2*df); chiSquare > limit {
+		c.fatalf("step %v: chi-square = %.1f for %v working buckets, limit = %.1f", step, chiSquare, n, limit)
+	}
+}
@@ -0,0 +1,66 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchortest
+
+import (
+	"testing"
+
+	"github.com/wdamron/go-anchorhash"
+)
+
+func TestAnchor(t *testing.T) {
+	Check(t, func() anchor.ConsistentHash { return anchor.NewAnchor(64, 48) }, Options{Seed: 1})
+}
+
+func TestCompactAnchor(t *testing.T) {
+	Check(t, func() anchor.ConsistentHash { return anchor.NewCompactAnchor(64, 48).ConsistentHash() }, Options{Seed: 2})
+}
+
+func TestTableAnchor(t *testing.T) {
+	Check(t, func() anchor.ConsistentHash { return anchor.NewTableAnchor(64, 48).ConsistentHash() }, Options{Seed: 3})
+}
+
+func TestTinyAnchor(t *testing.T) {
+	Check(t, func() anchor.ConsistentHash { return anchor.NewTinyAnchor(64, 48).ConsistentHash() }, Options{Seed: 7})
+}
+
+func TestJump(t *testing.T) {
+	Check(t, func() anchor.ConsistentHash { return anchor.NewJump(64, 48) }, Options{Seed: 4})
+}
+
+func TestRendezvous(t *testing.T) {
+	Check(t, func() anchor.ConsistentHash { return anchor.NewRendezvous(64, 48) }, Options{Seed: 5})
+}
+
+func TestRing(t *testing.T) {
+	// Ring hashes are only balanced to within a few percent, even with many replicas
+	Check(t, func() anchor.ConsistentHash { return anchor.NewRing(64, 48, 100) }, Options{Seed: 6, BalanceSigma: -1})
+}
+
+func TestHash(t *testing.T) {
+	Check(t, func() anchor.ConsistentHash { return anchor.New(64, 48) }, Options{Seed: 8})
+}
+
+func TestCompactHash(t *testing.T) {
+	Check(t, func() anchor.ConsistentHash { return anchor.NewCompact(64, 48).ConsistentHash() }, Options{Seed: 9})
+}
@@ -0,0 +1,100 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+// Number of keys processed together by GetBuckets.
+const batchSize = 8
+
+// Get the buckets which a slice of hash-keys are assigned to.
+//
+// The bucket for keys[i] will be stored in out[i]; out must be at least as long as keys.
+// Keys are processed in small batches: the initial bucket for every key in a batch is
+// loaded before any key is resolved, so the cache misses for independent keys overlap
+// rather than being paid one after another. For large anchors this is faster than calling
+// GetBucket for each key when most keys are assigned to their initial bucket. Keys whose
+// initial bucket was removed are still resolved one at a time, so the gain shrinks as more
+// buckets are removed.
+func (a *Anchor) GetBuckets(keys []uint64, out []uint32) {
+	A := a.A
+	out = out[:len(keys)]
+	var (
+		state [batchSize][4]uint32
+		bs    [batchSize]uint32
+		as    [batchSize]uint32
+	)
+	for len(keys) >= batchSize {
+		for i := range bs {
+			ha, hb, hc, hd := fleaInit(keys[i])
+			state[i] = [4]uint32{ha, hb, hc, hd}
+			bs[i] = fastMod(uint64(hd), uint64(len(A)))
+		}
+		for i := range as {
+			as[i] = A[bs[i]]
+		}
+		for i := range bs {
+			if as[i] == 0 {
+				out[i] = bs[i]
+			} else {
+				out[i] = resolve(A, a.K, bs[i], state[i])
+			}
+		}
+		keys, out = keys[batchSize:], out[batchSize:]
+	}
+	for i, key := range keys {
+		out[i] = a.GetBucket(key)
+	}
+}
+
+// Get the buckets which a slice of hash-keys are assigned to.
+//
+// See Anchor.GetBuckets for more information.
+func (a *CompactAnchor) GetBuckets(keys []uint64, out []uint16) {
+	A := a.A
+	out = out[:len(keys)]
+	var (
+		state [batchSize][4]uint32
+		bs    [batchSize]uint16
+		as    [batchSize]uint16
+	)
+	for len(keys) >= batchSize {
+		for i := range bs {
+			ha, hb, hc, hd := fleaInit(keys[i])
+			state[i] = [4]uint32{ha, hb, hc, hd}
+			bs[i] = uint16(fastMod(uint64(hd), uint64(len(A))))
+		}
+		for i := range as {
+			as[i] = A[bs[i]]
+		}
+		for i := range bs {
+			if as[i] == 0 {
+				out[i] = bs[i]
+			} else {
+				out[i] = resolve(A, a.K, bs[i], state[i])
+			}
+		}
+		keys, out = keys[batchSize:], out[batchSize:]
+	}
+	for i, key := range keys {
+		out[i] = a.GetBucket(key)
+	}
+}
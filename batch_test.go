@@ -0,0 +1,49 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import "testing"
+
+func TestGetBuckets(t *testing.T) {
+	a := NewAnchor(1000, 600)
+	c := NewCompactAnchor(1000, 600)
+	a.RemoveBucket(5)
+	c.RemoveBucket(5)
+
+	// Use a length which is not a multiple of the batch size
+	keys := make([]uint64, 10003)
+	for i := range keys {
+		keys[i] = uint64(i) * 0x9e3779b97f4a7c15
+	}
+	out, compactOut := make([]uint32, len(keys)), make([]uint16, len(keys))
+	a.GetBuckets(keys, out)
+	c.GetBuckets(keys, compactOut)
+	for i, key := range keys {
+		if b := a.GetBucket(key); out[i] != b {
+			t.Fatalf("key %v: batch bucket = %v, bucket = %v", key, out[i], b)
+		}
+		if b := c.GetBucket(key); compactOut[i] != b {
+			t.Fatalf("key %v: compact batch bucket = %v, bucket = %v", key, compactOut[i], b)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import "slices"
+
+// CanonicalizeReport describes the changes made by Canonicalize.
+type CanonicalizeReport struct {
+	// Changed is false if the anchor was already in canonical form, in which case it was
+	// not modified.
+	Changed bool
+	// Version is the version of the anchor after it was canonicalized.
+	Version uint64
+	// Keys is the number of sampled keys.
+	Keys int
+	// Moved is the number of sampled keys which were assigned to a different bucket after
+	// the anchor was canonicalized.
+	Moved int
+	// MovedFraction is Moved / Keys, or 0 if no keys were sampled.
+	MovedFraction float64
+}
+
+// Rebuild the anchor into the canonical form for its working set.
+//
+// Anchors with the same working set which were reached through different sequences of
+// AddBucket and RemoveBucket may assign keys to different buckets. Canonicalize replaces
+// the state of the anchor with the state created by NewAnchorFromWorkingSet for its
+// current working set, so all agents which canonicalize anchors with the same capacity and
+// working set will agree on the assignment of every key and on the order in which buckets
+// will be added. The working set is unchanged, but keys may move between working buckets.
+//
+// The fraction of keys which moved is estimated from the given sample of keys. If the
+// anchor was not already canonical, its version is incremented.
+func (a *Anchor) Canonicalize(sampleKeys []uint64) *CanonicalizeReport {
+	c, _ := NewAnchorFromWorkingSet(len(a.A), a.W[:a.N])
+	r := &CanonicalizeReport{Version: a.v, Keys: len(sampleKeys)}
+	if slices.Equal(a.A, c.A) && slices.Equal(a.K, c.K) && slices.Equal(a.W, c.W) &&
+		slices.Equal(a.L, c.L) && slices.Equal(a.R, c.R) {
+		return r
+	}
+	for _, key := range sampleKeys {
+		if a.GetBucket(key) != c.GetBucket(key) {
+			r.Moved++
+		}
+	}
+	c.v = a.v + 1
+	*a = *c
+	r.finish(a.v)
+	return r
+}
+
+// Rebuild the compact anchor into the canonical form for its working set.
+//
+// See Anchor.Canonicalize for more information.
+func (a *CompactAnchor) Canonicalize(sampleKeys []uint64) *CanonicalizeReport {
+	c, _ := NewCompactAnchorFromWorkingSet(len(a.A), a.W[:a.N])
+	r := &CanonicalizeReport{Version: a.v, Keys: len(sampleKeys)}
+	if slices.Equal(a.A, c.A) && slices.Equal(a.K, c.K) && slices.Equal(a.W, c.W) &&
+		slices.Equal(a.L, c.L) && slices.Equal(a.R, c.R) {
+		return r
+	}
+	for _, key := range sampleKeys {
+		if a.GetBucket(key) != c.GetBucket(key) {
+			r.Moved++
+		}
+	}
+	c.v = a.v + 1
+	*a = *c
+	r.finish(a.v)
+	return r
+}
+
+// Rebuild the hash into the canonical form for its working set.
+//
+// See Anchor.Canonicalize for more information.
+func (h *Hash) Canonicalize(sampleKeys []uint64) *CanonicalizeReport {
+	return h.a.Canonicalize(sampleKeys)
+}
+
+// Rebuild the hash into the canonical form for its working set.
+//
+// See Anchor.Canonicalize for more information.
+func (h *CompactHash) Canonicalize(sampleKeys []uint64) *CanonicalizeReport {
+	return h.a.Canonicalize(sampleKeys)
+}
+
+func (r *CanonicalizeReport) finish(version uint64) {
+	r.Changed, r.Version = true, version
+	if r.Keys > 0 {
+		r.MovedFraction = float64(r.Moved) / float64(r.Keys)
+	}
+}
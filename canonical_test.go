@@ -0,0 +1,92 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	keys := make([]uint64, 1e4)
+	for i := range keys {
+		keys[i] = uint64(i)
+	}
+
+	// Two agents remove the same buckets in different orders
+	a, b := NewAnchor(16, 16), NewAnchor(16, 16)
+	for _, bucket := range []uint32{2, 7, 11, 4} {
+		a.RemoveBucket(bucket)
+	}
+	for _, bucket := range []uint32{11, 4, 2, 7} {
+		b.RemoveBucket(bucket)
+	}
+	diverged := 0
+	for _, key := range keys {
+		if a.GetBucket(key) != b.GetBucket(key) {
+			diverged++
+		}
+	}
+	if diverged == 0 {
+		t.Fatalf("anchors with different removal orders assign all keys to the same buckets")
+	}
+
+	before := a.Freeze()
+	r := a.Canonicalize(keys)
+	moved := 0
+	for _, key := range keys {
+		if before.GetBucket(key) != a.GetBucket(key) {
+			moved++
+		}
+	}
+	if !r.Changed || r.Version != 5 || a.Version() != 5 || r.Keys != len(keys) || r.Moved != moved ||
+		r.MovedFraction != float64(moved)/float64(len(keys)) {
+		t.Fatalf("report = %+v, expected %v of %v keys moved at version 5", r, moved, len(keys))
+	}
+	b.Canonicalize(nil)
+	if !reflect.DeepEqual(a.A, b.A) || !reflect.DeepEqual(a.K, b.K) || !reflect.DeepEqual(a.W, b.W) || !reflect.DeepEqual(a.R, b.R) {
+		t.Fatalf("canonical anchors differ")
+	}
+	expected, _ := NewAnchorFromWorkingSet(16, a.W[:a.N])
+	expected.v = a.v
+	if !reflect.DeepEqual(a, expected) {
+		t.Fatalf("canonical anchor = %+v, expected %+v", a, expected)
+	}
+
+	// Canonical anchors are left untouched
+	if r := a.Canonicalize(keys); r.Changed || r.Moved != 0 || r.Version != 5 || a.Version() != 5 {
+		t.Fatalf("report = %+v for a canonical anchor", r)
+	}
+	c := NewCompactAnchor(16, 12)
+	if r := c.Canonicalize(keys); r.Changed || c.Version() != 0 {
+		t.Fatalf("report = %+v for a new compact anchor", r)
+	}
+	c.RemoveBucket(3)
+	c.RemoveBucket(1)
+	c.Canonicalize(nil)
+	expectedCompact, _ := NewCompactAnchorFromWorkingSet(16, c.W[:c.N])
+	expectedCompact.v = c.v
+	if !reflect.DeepEqual(c, expectedCompact) {
+		t.Fatalf("canonical compact anchor = %+v, expected %+v", c, expectedCompact)
+	}
+}
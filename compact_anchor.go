@@ -42,12 +42,19 @@ type CompactAnchor struct {
 	L []uint16
 	// R saves removed buckets in a LIFO order for possible future bucket additions.
 	R []uint16
-	// N is the current length of W
-	N uint16
+	// N is the current length of W. N is stored as an unsigned 32-bit integer, so a full
+	// working set of 65,536 buckets may be represented.
+	N uint32
+
+	// v is incremented by each change to the working set
+	v uint64
 }
 
 // Create a new anchor with a given capacity and initial size.
 //
+// Buckets 0 through used-1 will be working. At least one bucket must be working, and the
+// capacity may not exceed 65,536 buckets; NewCompactAnchor panics otherwise.
+//
 // 	INITANCHOR(a, w)
 // 	A[b] ← 0 for b = 0, 1, ..., a−1    ◃ |Wb| ← 0 for b ∈ A
 // 	R ← ∅                              ◃ Empty stack
@@ -55,20 +62,23 @@ type CompactAnchor struct {
 // 	K[b] ← L[b] ← W[b] ← b for b = 0, 1, ..., a−1
 // 	for b = a−1 downto w do            ◃ Remove initially unused buckets
 // 	  REMOVEBUCKET(b)
-func NewCompactAnchor(buckets, used uint16) *CompactAnchor {
+func NewCompactAnchor(buckets, used int) *CompactAnchor {
+	if buckets > 1<<16 || used < 1 || used > buckets {
+		panic("anchor: invalid capacity or size for a compact anchor")
+	}
 	a := &CompactAnchor{
 		A: make([]uint16, buckets),
 		K: make([]uint16, buckets),
 		W: make([]uint16, buckets),
 		L: make([]uint16, buckets),
 		R: make([]uint16, buckets-used, buckets),
-		N: uint16(used),
+		N: uint32(used),
 	}
-	for b := uint16(0); b < uint16(used); b++ {
-		a.K[b], a.W[b], a.L[b] = b, b, b
+	for b := 0; b < buckets; b++ {
+		a.K[b], a.W[b], a.L[b] = uint16(b), uint16(b), uint16(b)
 	}
-	for b, r := uint16(buckets)-1, 0; b >= uint16(used); b, r = b-1, r+1 {
-		a.A[b], a.R[r] = b, b
+	for b, r := buckets-1, 0; b >= used; b, r = b-1, r+1 {
+		a.A[b], a.R[r] = uint16(b), uint16(b)
 	}
 	return a
 }
@@ -90,18 +100,7 @@ func NewCompactAnchor(buckets, used uint16) *CompactAnchor {
 // 	  b ← h
 // 	return b
 func (a *CompactAnchor) GetBucket(key uint64) uint16 {
-	A, K := a.A, a.K
-	ha, hb, hc, hd := fleaInit(key)
-	b := uint16(fastMod(uint64(hd), uint64(len(A))))
-	for A[b] > 0 {
-		ha, hb, hc, hd = fleaRound(ha, hb, hc, hd)
-		h := uint16(fastMod(uint64(hd), uint64(A[b])))
-		for A[h] >= A[b] {
-			h = K[h]
-		}
-		b = h
-	}
-	return b
+	return getBucket(a.A, a.K, key)
 }
 
 // Get the path to the bucket which a hash-key is assigned to.
@@ -131,25 +130,14 @@ func (a *CompactAnchor) GetBucket(key uint64) uint16 {
 // 	  b ← h
 // 	return P
 func (a *CompactAnchor) GetPath(key uint64, pathBuffer []uint16) []uint16 {
-	A, K := a.A, a.K
-	ha, hb, hc, hd := fleaInit(key)
-	b := uint16(fastMod(uint64(hd), uint64(len(A))))
-	pathBuffer = append(pathBuffer, b)
-	for A[b] > 0 {
-		ha, hb, hc, hd = fleaRound(ha, hb, hc, hd)
-		h := uint16(fastMod(uint64(hd), uint64(A[b])))
-		pathBuffer = append(pathBuffer, h)
-		for A[h] >= A[b] {
-			h = K[h]
-			pathBuffer = append(pathBuffer, h)
-		}
-		b = h
-	}
-	return pathBuffer
+	return getPath(a.A, a.K, key, pathBuffer)
 }
 
 // Add a bucket to the anchor.
 //
+// The most recently removed bucket will be added. AddBucket panics if all buckets are
+// working.
+//
 // 	ADDBUCKET()
 // 	b ← R.pop()
 // 	A[b] ← 0       ◃ W ← W ∪ {b}, delete Wb
@@ -158,18 +146,20 @@ func (a *CompactAnchor) GetPath(key uint64, pathBuffer []uint16) []uint16 {
 // 	N ← N + 1
 // 	return b
 func (a *CompactAnchor) AddBucket() uint16 {
-	A, K, W, L, R, N := a.A, a.K, a.W, a.L, a.R, a.N
-	b := R[len(R)-1]
-	a.R = R[:len(R)-1]
-	A[b] = 0
-	L[W[N]] = N
-	W[L[b]], K[b] = b, b
+	if len(a.R) == 0 {
+		panic("anchor: no buckets to add")
+	}
+	b, R := addBucket(a.A, a.K, a.W, a.L, a.R, uint32(a.N))
+	a.R = R
 	a.N++
+	a.v++
 	return b
 }
 
 // Remove a bucket from the anchor.
 //
+// Removing a bucket which is not working, or the last working bucket, has no effect.
+//
 // 	REMOVEBUCKET(b)
 // 	R.push(b)
 // 	N ← N − 1
@@ -177,13 +167,15 @@ func (a *CompactAnchor) AddBucket() uint16 {
 // 	W[L[b]] ← K[b] ← W[N]
 // 	L[W[N]] ← L[b]
 func (a *CompactAnchor) RemoveBucket(b uint16) {
-	if a.A[b] != 0 {
+	if int(b) >= len(a.A) || a.A[b] != 0 || a.N == 1 {
 		return
 	}
 	a.N--
-	A, K, W, L, N := a.A, a.K, a.W, a.L, a.N
-	a.R = append(a.R, b)
-	A[b] = N
-	W[L[b]], K[b] = W[N], W[N]
-	L[W[N]] = L[b]
+	a.R = removeBucket(a.A, a.K, a.W, a.L, a.R, b, uint32(a.N))
+	a.v++
 }
+
+// Get the version of the anchor.
+//
+// See Anchor.Version for more information.
+func (a *CompactAnchor) Version() uint64 { return a.v }
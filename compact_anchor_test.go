@@ -0,0 +1,97 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompactAnchorMaxCapacity(t *testing.T) {
+	const buckets = 1 << 16
+	c := NewCompactAnchor(buckets, buckets)
+	a := NewAnchor(buckets, buckets)
+	if c.N != buckets {
+		t.Fatalf("N = %v, expected %v", c.N, buckets)
+	}
+
+	check := func() {
+		t.Helper()
+		for k := uint64(0); k < 1e5; k++ {
+			if b, cb := a.GetBucket(k), c.GetBucket(k); uint32(cb) != b {
+				t.Fatalf("key %v: compact bucket = %v, bucket = %v", k, cb, b)
+			}
+		}
+	}
+	check()
+
+	for _, b := range []uint32{buckets - 1, 0, 12345, buckets - 2} {
+		a.RemoveBucket(b)
+		c.RemoveBucket(uint16(b))
+		check()
+	}
+	for i := 0; i < 4; i++ {
+		if b, cb := a.AddBucket(), c.AddBucket(); uint32(cb) != b {
+			t.Fatalf("compact added bucket %v, anchor added bucket %v", cb, b)
+		}
+		check()
+	}
+	if c.N != buckets {
+		t.Fatalf("N = %v after restoring all buckets, expected %v", c.N, buckets)
+	}
+
+	data, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded CompactAnchor
+	if err = decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, c) {
+		t.Fatal("decoded compact anchor differs from the encoded anchor")
+	}
+
+	half := NewCompactAnchor(buckets, buckets/2)
+	if b := half.AddBucket(); b != buckets/2 {
+		t.Fatalf("added bucket %v, expected %v", b, buckets/2)
+	}
+}
+
+func TestCompactAnchorInvalidSize(t *testing.T) {
+	for _, size := range [][2]int{{1<<16 + 1, 1}, {4, 0}, {4, 5}, {0, 0}} {
+		for name, create := range map[string]func(buckets, used int){
+			"compact": func(buckets, used int) { NewCompactAnchor(buckets, used) },
+			"table":   func(buckets, used int) { NewTableAnchor(buckets, used) },
+		} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Fatalf("%v: capacity %v with %v working buckets did not panic", name, size[0], size[1])
+					}
+				}()
+				create(size[0], size[1])
+			}()
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import "iter"
+
+// Compact AnchorHash implementation with private state.
+//
+// See Hash and CompactAnchor for more information.
+type CompactHash struct {
+	a CompactAnchor
+}
+
+// Create a new compact hash with a given capacity and initial size.
+//
+// Buckets 0 through used-1 will be working. See NewCompactAnchor for more information.
+func NewCompact(buckets, used int) *CompactHash {
+	return &CompactHash{a: *NewCompactAnchor(buckets, used)}
+}
+
+// Create a new compact hash from a copy of the state of a compact anchor.
+//
+// See FromAnchor for more information.
+func FromCompactAnchor(a *CompactAnchor) (*CompactHash, error) {
+	if !a.valid() {
+		return nil, ErrInvalidState
+	}
+	return &CompactHash{a: *a.clone()}, nil
+}
+
+// Get a copy of the state of the hash as a compact anchor. Changes to the compact anchor
+// will not affect the hash.
+func (h *CompactHash) CompactAnchor() *CompactAnchor { return h.a.clone() }
+
+// Get the bucket which a hash-key is assigned to.
+//
+// See CompactAnchor.GetBucket for more information.
+func (h *CompactHash) GetBucket(key uint64) uint16 { return h.a.GetBucket(key) }
+
+// Get the path to the bucket which a hash-key is assigned to.
+//
+// See CompactAnchor.GetPath for more information.
+func (h *CompactHash) GetPath(key uint64, pathBuffer []uint16) []uint16 {
+	return h.a.GetPath(key, pathBuffer)
+}
+
+// Get the buckets which a slice of hash-keys are assigned to.
+//
+// See CompactAnchor.GetBuckets for more information.
+func (h *CompactHash) GetBuckets(keys []uint64, out []uint16) { h.a.GetBuckets(keys, out) }
+
+// Add a bucket to the hash and return it.
+//
+// See CompactAnchor.AddBucket for more information.
+func (h *CompactHash) AddBucket() uint16 { return h.a.AddBucket() }
+
+// Remove a bucket from the hash.
+//
+// See CompactAnchor.RemoveBucket for more information.
+func (h *CompactHash) RemoveBucket(b uint16) { h.a.RemoveBucket(b) }
+
+// Get the version of the hash.
+//
+// See Anchor.Version for more information.
+func (h *CompactHash) Version() uint64 { return h.a.v }
+
+// Get a ConsistentHash which reports the buckets of the hash as unsigned 32-bit integers.
+func (h *CompactHash) ConsistentHash() ConsistentHash { return widened[uint16, *CompactHash]{h} }
+
+// Get the number of working buckets.
+func (h *CompactHash) Len() int { return int(h.a.N) }
+
+// Get the total number of buckets.
+func (h *CompactHash) Capacity() int { return len(h.a.A) }
+
+// Get the number of removed buckets.
+func (h *CompactHash) Removed() int { return len(h.a.R) }
+
+// Check if b is a working bucket.
+func (h *CompactHash) IsWorking(b uint16) bool { return h.a.IsWorking(b) }
+
+// Iterate over the working buckets in their desired order.
+//
+// The hash must not be modified during iteration.
+func (h *CompactHash) WorkingBuckets() iter.Seq[uint16] { return h.a.WorkingBuckets() }
+
+// Get a copy of the removed buckets in the order in which they were removed.
+func (h *CompactHash) RemovedBuckets() []uint16 { return h.a.RemovedBuckets() }
+
+// Get the approximate number of bytes of memory retained by the hash.
+func (h *CompactHash) MemoryBytes() int { return h.a.MemoryBytes() }
+
+// Create an immutable, lookup-only copy of the hash.
+//
+// See CompactAnchor.Freeze for more information.
+func (h *CompactHash) Freeze() *FrozenCompactAnchor { return h.a.Freeze() }
+
+// Get the changes to A and K since a frozen copy of the hash was created.
+//
+// See CompactAnchor.Delta for more information.
+func (h *CompactHash) Delta(base *FrozenCompactAnchor) (*Delta, error) { return h.a.Delta(base) }
+
+// Explain why a hash-key is assigned to its bucket.
+//
+// See Anchor.Explain for more information.
+func (h *CompactHash) Explain(key uint64) *Explanation { return h.a.Explain(key) }
+
+// Compute distribution and path-length statistics over a sample of hash-keys.
+//
+// See Anchor.Stats for more information.
+func (h *CompactHash) Stats(sampleKeys []uint64) *Stats { return h.a.Stats(sampleKeys) }
+
+// Convert the compact hash to a hash.
+//
+// See CompactAnchor.ToAnchor for more information.
+func (h *CompactHash) ToHash() *Hash { return &Hash{a: *h.a.ToAnchor()} }
+
+// Encode the hash into a binary form, which is identical to the encoding of a CompactAnchor.
+func (h *CompactHash) MarshalBinary() ([]byte, error) { return h.a.MarshalBinary() }
+
+// Decode a hash from the binary form produced by MarshalBinary.
+//
+// See CompactAnchor.UnmarshalBinary for more information.
+func (h *CompactHash) UnmarshalBinary(data []byte) error { return h.a.UnmarshalBinary(data) }
+
+// Get a read-only view of A, which holds |Wb| for each removed bucket b, or else 0.
+func (h *CompactHash) A() CompactView { return CompactView{h.a.A} }
+
+// Get a read-only view of K, which holds the successor of each removed bucket.
+func (h *CompactHash) K() CompactView { return CompactView{h.a.K} }
+
+// Get a read-only view of W, which holds the working buckets in their desired order.
+func (h *CompactHash) W() CompactView { return CompactView{h.a.W[:h.a.N]} }
+
+// Get a read-only view of L, which holds the most recent location of each bucket within W.
+func (h *CompactHash) L() CompactView { return CompactView{h.a.L} }
+
+// Get a read-only view of R, which holds the removed buckets in removal order.
+func (h *CompactHash) R() CompactView { return CompactView{h.a.R} }
+
+// Read-only view of an array within a CompactHash.
+//
+// See View for more information.
+type CompactView struct {
+	s []uint16
+}
+
+// Get the length of the array.
+func (v CompactView) Len() int { return len(v.s) }
+
+// Get the element at index i. At panics if i is out of range.
+func (v CompactView) At(i int) uint16 { return v.s[i] }
+
+// Iterate over the indices and elements of the array.
+func (v CompactView) All() iter.Seq2[int, uint16] {
+	return func(yield func(int, uint16) bool) {
+		for i, x := range v.s {
+			if !yield(i, x) {
+				return
+			}
+		}
+	}
+}
+
+// Append the elements of the array to dst and return the extended slice.
+func (v CompactView) AppendTo(dst []uint16) []uint16 { return append(dst, v.s...) }
+
+// Create a deep copy of the compact anchor.
+func (a *CompactAnchor) clone() *CompactAnchor {
+	R := make([]uint16, len(a.R), len(a.A))
+	copy(R, a.R)
+	return &CompactAnchor{
+		A: append([]uint16(nil), a.A...),
+		K: append([]uint16(nil), a.K...),
+		W: append([]uint16(nil), a.W...),
+		L: append([]uint16(nil), a.L...),
+		R: R,
+		N: a.N,
+		v: a.v,
+	}
+}
+
+// Check if the compact anchor is in a state reachable through NewCompactAnchor, AddBucket
+// and RemoveBucket.
+func (a *CompactAnchor) valid() bool {
+	return len(a.A) <= 1<<16 && validAnchor(a.A, a.K, a.W, a.L, a.R, a.N)
+}
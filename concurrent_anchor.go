@@ -0,0 +1,103 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// AnchorHash implementation which is safe for concurrent use by multiple goroutines.
+//
+// Lookups read an immutable FrozenAnchor, which is replaced atomically after each change,
+// so readers are never blocked and never observe an intermediate state of a batch of
+// changes. Changes are serialized, and each change copies A and K, so a concurrent anchor
+// suits workloads where lookups vastly outnumber changes.
+type ConcurrentAnchor struct {
+	mu sync.Mutex
+	// a is the state used to make changes, which is only accessed while mu is held.
+	a *Anchor
+	// f is the latest published state.
+	f atomic.Pointer[FrozenAnchor]
+}
+
+// Create a new concurrent anchor with a given capacity and initial size.
+//
+// See NewAnchor for more information.
+func NewConcurrentAnchor(buckets, used int) *ConcurrentAnchor {
+	c := &ConcurrentAnchor{a: NewAnchor(buckets, used)}
+	c.f.Store(c.a.Freeze())
+	return c
+}
+
+// Get the bucket which a hash-key is assigned to.
+//
+// See Anchor.GetBucket for more information.
+func (c *ConcurrentAnchor) GetBucket(key uint64) uint32 { return c.f.Load().GetBucket(key) }
+
+// Get the latest published state of the anchor.
+//
+// Multiple lookups made through the returned anchor will observe the same state, even
+// while changes are being made.
+func (c *ConcurrentAnchor) Snapshot() *FrozenAnchor { return c.f.Load() }
+
+// Get the version of the latest published state of the anchor.
+func (c *ConcurrentAnchor) Version() uint64 { return c.f.Load().Version() }
+
+// Add a bucket to the anchor and return it.
+//
+// See Anchor.AddBucket for more information.
+func (c *ConcurrentAnchor) AddBucket() uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.a.AddBucket()
+	c.f.Store(c.a.Freeze())
+	return b
+}
+
+// Remove a bucket from the anchor.
+//
+// See Anchor.RemoveBucket for more information.
+func (c *ConcurrentAnchor) RemoveBucket(b uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v := c.a.v
+	c.a.RemoveBucket(b)
+	if c.a.v != v {
+		c.f.Store(c.a.Freeze())
+	}
+}
+
+// Apply a batch of operations to the anchor, or none of them, and publish the final
+// state atomically.
+//
+// See Anchor.ApplyBatch for more information.
+func (c *ConcurrentAnchor) ApplyBatch(ops []Op) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.a.ApplyBatch(ops); err != nil {
+		return err
+	}
+	c.f.Store(c.a.Freeze())
+	return nil
+}
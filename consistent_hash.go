@@ -0,0 +1,126 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+// ConsistentHash is implemented by each consistent-hash algorithm in this package, so
+// algorithms may be swapped and compared with a single harness.
+//
+// Anchor, Hash, Jump, Rendezvous and Ring implement ConsistentHash directly. Anchors which
+// store buckets in fewer than 32 bits provide an implementation through their
+// ConsistentHash method.
+//
+// Jump can only remove the last working bucket. RemoveBucket has no effect on a Jump for
+// any other bucket, so it does not support arbitrary removals.
+type ConsistentHash interface {
+	// Get the bucket which a hash-key is assigned to.
+	GetBucket(key uint64) uint32
+	// Add a bucket and return it. Buckets are added in the reverse order of their removal.
+	AddBucket() uint32
+	// Remove a working bucket. Removing a bucket which is not working has no effect.
+	RemoveBucket(b uint32)
+	// Get the number of working buckets.
+	Len() int
+	// Get the total number of buckets, including removed buckets.
+	Capacity() int
+}
+
+var (
+	_ ConsistentHash = (*Anchor)(nil)
+	_ ConsistentHash = (*Hash)(nil)
+	_ ConsistentHash = (*Jump)(nil)
+	_ ConsistentHash = (*Rendezvous)(nil)
+	_ ConsistentHash = (*Ring)(nil)
+)
+
+// Get the number of working buckets.
+func (a *Anchor) Len() int { return int(a.N) }
+
+// Get the total number of buckets, including removed buckets.
+func (a *Anchor) Capacity() int { return len(a.A) }
+
+// Get a ConsistentHash which reports the buckets of the anchor as unsigned 32-bit integers.
+func (a *CompactAnchor) ConsistentHash() ConsistentHash { return widened[uint16, *CompactAnchor]{a} }
+
+// Get the number of working buckets.
+func (a *CompactAnchor) Len() int { return int(a.N) }
+
+// Get the total number of buckets, including removed buckets.
+func (a *CompactAnchor) Capacity() int { return len(a.A) }
+
+// Get a ConsistentHash which reports the buckets of the anchor as unsigned 32-bit integers.
+func (t *TableAnchor) ConsistentHash() ConsistentHash { return widened[uint16, *TableAnchor]{t} }
+
+// Get the number of working buckets.
+func (t *TableAnchor) Len() int { return int(t.a.N) }
+
+// Get the total number of buckets, including removed buckets.
+func (t *TableAnchor) Capacity() int { return len(t.a.A) }
+
+// Get a ConsistentHash which reports the buckets of the anchor as unsigned 32-bit integers.
+func (a *TinyAnchor) ConsistentHash() ConsistentHash { return widened[uint8, *TinyAnchor]{a} }
+
+// Get the number of working buckets.
+func (a *TinyAnchor) Len() int { return int(a.N) }
+
+// Get the total number of buckets, including removed buckets.
+func (a *TinyAnchor) Capacity() int { return len(a.A) }
+
+// narrowHash is implemented by anchors which store buckets in fewer than 32 bits.
+type narrowHash[T bucket] interface {
+	GetBucket(key uint64) T
+	AddBucket() T
+	RemoveBucket(b T)
+	Len() int
+	Capacity() int
+}
+
+// widened implements ConsistentHash for a narrowHash. Buckets which cannot be represented
+// by the narrowHash are ignored by RemoveBucket.
+type widened[T bucket, H narrowHash[T]] struct{ h H }
+
+func (w widened[T, H]) GetBucket(key uint64) uint32 { return uint32(w.h.GetBucket(key)) }
+
+func (w widened[T, H]) AddBucket() uint32 { return uint32(w.h.AddBucket()) }
+
+func (w widened[T, H]) RemoveBucket(b uint32) {
+	if b < uint32(w.h.Capacity()) {
+		w.h.RemoveBucket(T(b))
+	}
+}
+
+func (w widened[T, H]) Len() int { return w.h.Len() }
+
+func (w widened[T, H]) Capacity() int { return w.h.Capacity() }
+
+// Mix the bits of a 64-bit value. This is the finalizer from SplitMix64, which is used by
+// the alternative algorithms to spread sequential keys and buckets across the hash space.
+//
+// See "Fast Splittable Pseudorandom Number Generators" (Steele, Lea, Flood, 2014)
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,85 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import "testing"
+
+func TestConsistentHash(t *testing.T) {
+	const (
+		buckets = 20
+		used    = 15
+	)
+	hashes := map[string]ConsistentHash{
+		"Anchor":        NewAnchor(buckets, buckets),
+		"CompactAnchor": NewCompactAnchor(buckets, buckets).ConsistentHash(),
+		"TableAnchor":   NewTableAnchor(buckets, buckets).ConsistentHash(),
+		"TinyAnchor":    NewTinyAnchor(buckets, buckets).ConsistentHash(),
+		"Hash":          New(buckets, buckets),
+		"CompactHash":   NewCompact(buckets, buckets).ConsistentHash(),
+		"Jump":          NewJump(buckets, buckets),
+		"Rendezvous":    NewRendezvous(buckets, buckets),
+		"Ring":          NewRing(buckets, buckets, 100),
+	}
+	for name, h := range hashes {
+		for b := uint32(buckets - 1); b >= used; b-- {
+			h.RemoveBucket(b)
+		}
+		if h.Len() != used || h.Capacity() != buckets {
+			t.Fatalf("%v: len = %v, capacity = %v", name, h.Len(), h.Capacity())
+		}
+		counts := make([]int, buckets)
+		for k := uint64(0); k < 1e5; k++ {
+			counts[h.GetBucket(k)]++
+		}
+		for b, count := range counts {
+			if working := b < used; working != (count > 0) {
+				t.Fatalf("%v: %v keys assigned to bucket %v", name, count, b)
+			}
+		}
+		if b := h.AddBucket(); b != used {
+			t.Fatalf("%v: added bucket %v, expected %v", name, b, used)
+		}
+	}
+}
+
+func TestConsistentHashInvalidSize(t *testing.T) {
+	for _, size := range [][3]int{{10, 0, 1}, {10, 11, 1}, {0, 0, 1}, {-1, 1, 1}, {10, 5, 0}} {
+		for name, create := range map[string]func(buckets, used, replicas int){
+			"jump":       func(buckets, used, replicas int) { NewJump(buckets, used) },
+			"rendezvous": func(buckets, used, replicas int) { NewRendezvous(buckets, used) },
+			"ring":       func(buckets, used, replicas int) { NewRing(buckets, used, replicas) },
+		} {
+			if name != "ring" && size[2] < 1 {
+				continue
+			}
+			func() {
+				defer func() {
+					if r := recover(); r == nil {
+						t.Fatalf("%v: capacity %v with %v working buckets and %v replicas did not panic", name, size[0], size[1], size[2])
+					}
+				}()
+				create(size[0], size[1], size[2])
+			}()
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import "errors"
+
+// ErrTooLarge is returned when converting an anchor to a compact anchor whose buckets
+// cannot be represented as unsigned 16-bit integers.
+var ErrTooLarge = errors.New("anchor: too many buckets for a compact anchor")
+
+// Convert the anchor to a compact anchor.
+//
+// The compact anchor will assign every key to the same bucket as the anchor, and will
+// add buckets in the same order. ErrTooLarge will be returned if the capacity of the
+// anchor exceeds the capacity of a compact anchor.
+func (a *Anchor) ToCompact() (*CompactAnchor, error) {
+	if len(a.A) > 1<<16 {
+		return nil, ErrTooLarge
+	}
+	c := &CompactAnchor{
+		A: make([]uint16, len(a.A)),
+		K: make([]uint16, len(a.K)),
+		W: make([]uint16, len(a.W)),
+		L: make([]uint16, len(a.L)),
+		R: make([]uint16, len(a.R), len(a.A)),
+		N: a.N,
+		v: a.v,
+	}
+	for b := range a.A {
+		c.A[b], c.K[b], c.W[b], c.L[b] = uint16(a.A[b]), uint16(a.K[b]), uint16(a.W[b]), uint16(a.L[b])
+	}
+	for i, b := range a.R {
+		c.R[i] = uint16(b)
+	}
+	return c, nil
+}
+
+// Convert the compact anchor to an anchor.
+//
+// The anchor will assign every key to the same bucket as the compact anchor, and will
+// add buckets in the same order.
+func (c *CompactAnchor) ToAnchor() *Anchor {
+	a := &Anchor{
+		A: make([]uint32, len(c.A)),
+		K: make([]uint32, len(c.K)),
+		W: make([]uint32, len(c.W)),
+		L: make([]uint32, len(c.L)),
+		R: make([]uint32, len(c.R), len(c.A)),
+		N: c.N,
+		v: c.v,
+	}
+	for b := range c.A {
+		a.A[b], a.K[b], a.W[b], a.L[b] = uint32(c.A[b]), uint32(c.K[b]), uint32(c.W[b]), uint32(c.L[b])
+	}
+	for i, b := range c.R {
+		a.R[i] = uint32(b)
+	}
+	return a
+}
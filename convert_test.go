@@ -0,0 +1,59 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	a := NewAnchor(1000, 800)
+	a.RemoveBucket(10)
+	a.RemoveBucket(700)
+	a.AddBucket()
+	a.RemoveBucket(0)
+
+	c, err := a.ToCompact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := uint64(0); k < 1e4; k++ {
+		if b, cb := a.GetBucket(k), c.GetBucket(k); uint32(cb) != b {
+			t.Fatalf("key %v: compact bucket = %v, bucket = %v", k, cb, b)
+		}
+	}
+	if b, cb := a.AddBucket(), c.AddBucket(); uint32(cb) != b {
+		t.Fatalf("compact added bucket %v, anchor added bucket %v", cb, b)
+	}
+	if back := c.ToAnchor(); !reflect.DeepEqual(back, a) {
+		t.Fatalf("converted back = %#+v, anchor = %#+v", *back, *a)
+	}
+
+	if _, err = NewAnchor(1<<16, 1<<16).ToCompact(); err != nil {
+		t.Fatalf("converting 65,536 buckets: %v", err)
+	}
+	if _, err = NewAnchor(1<<16+1, 1<<16).ToCompact(); err != ErrTooLarge {
+		t.Fatalf("converted 65,537 buckets, err = %v", err)
+	}
+}
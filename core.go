@@ -0,0 +1,105 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+// The algorithms of AnchorHash are shared by every anchor type through the functions in
+// this file, which are generic over the integer type used to store buckets. Every type
+// must assign keys to identical buckets for the same sequence of changes.
+
+// bucket is the type used to store buckets and working set sizes in A, K, W, L and R.
+type bucket interface{ ~uint8 | ~uint16 | ~uint32 }
+
+// Get the bucket which a hash-key is assigned to.
+//
+// See Anchor.GetBucket for more information.
+func getBucket[T bucket](A, K []T, key uint64) T {
+	ha, hb, hc, hd := fleaInit(key)
+	b := T(fastMod(uint64(hd), uint64(len(A))))
+	for A[b] > 0 {
+		ha, hb, hc, hd = fleaRound(ha, hb, hc, hd)
+		h := T(fastMod(uint64(hd), uint64(A[b])))
+		for A[h] >= A[b] {
+			h = K[h]
+		}
+		b = h
+	}
+	return b
+}
+
+// Get the path to the bucket which a hash-key is assigned to.
+//
+// See Anchor.GetPath for more information.
+func getPath[T bucket](A, K []T, key uint64, pathBuffer []T) []T {
+	ha, hb, hc, hd := fleaInit(key)
+	b := T(fastMod(uint64(hd), uint64(len(A))))
+	pathBuffer = append(pathBuffer, b)
+	for A[b] > 0 {
+		ha, hb, hc, hd = fleaRound(ha, hb, hc, hd)
+		h := T(fastMod(uint64(hd), uint64(A[b])))
+		pathBuffer = append(pathBuffer, h)
+		for A[h] >= A[b] {
+			h = K[h]
+			pathBuffer = append(pathBuffer, h)
+		}
+		b = h
+	}
+	return pathBuffer
+}
+
+// Continue the search for a working bucket from a removed bucket b, with the hash state
+// left by the search so far.
+func resolve[T bucket](A, K []T, b T, state [4]uint32) T {
+	ha, hb, hc, hd := state[0], state[1], state[2], state[3]
+	for A[b] > 0 {
+		ha, hb, hc, hd = fleaRound(ha, hb, hc, hd)
+		h := T(fastMod(uint64(hd), uint64(A[b])))
+		for A[h] >= A[b] {
+			h = K[h]
+		}
+		b = h
+	}
+	return b
+}
+
+// Add the most recently removed bucket to a working set of size N, returning the bucket
+// and the remaining removed buckets. R must not be empty.
+//
+// See Anchor.AddBucket for more information.
+func addBucket[T bucket](A, K, W, L, R []T, N uint32) (T, []T) {
+	b := R[len(R)-1]
+	A[b] = 0
+	L[W[N]] = T(N)
+	W[L[b]], K[b] = b, b
+	return b, R[:len(R)-1]
+}
+
+// Remove a working bucket b, leaving a working set of size N, and return the removed
+// buckets. b must be working, and must not be the last working bucket.
+//
+// See Anchor.RemoveBucket for more information.
+func removeBucket[T bucket](A, K, W, L, R []T, b T, N uint32) []T {
+	A[b] = T(N)
+	W[L[b]], K[b] = W[N], W[N]
+	L[W[N]] = L[b]
+	return append(R, b)
+}
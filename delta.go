@@ -0,0 +1,222 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	// ErrVersionMismatch is returned when a delta is applied to a frozen anchor other than
+	// the version it was created from.
+	ErrVersionMismatch = errors.New("anchor: delta does not apply to this version")
+	// ErrCapacityMismatch is returned when anchors or deltas of different capacities are
+	// combined.
+	ErrCapacityMismatch = errors.New("anchor: capacity mismatch")
+	// ErrInvalidDelta is returned when a delta is malformed or contains entries which do
+	// not fit the anchor it is applied to.
+	ErrInvalidDelta = errors.New("anchor: invalid delta")
+)
+
+// Delta describes the entries of A and K changed by a sequence of AddBucket and
+// RemoveBucket operations.
+//
+// Each operation changes A[b] and K[b] for a single bucket b, so a delta will contain at
+// most one entry for each bucket added or removed since the base version. A delta may be
+// sent from a control plane which owns an Anchor to clients which only hold a frozen,
+// lookup-only copy of it.
+type Delta struct {
+	// From is the version of the anchor which the delta applies to.
+	From uint64
+	// To is the version of the anchor after the delta has been applied.
+	To uint64
+	// Capacity is the total number of buckets in the anchor.
+	Capacity uint32
+	// Entries holds the changed entries in ascending order of bucket.
+	Entries []DeltaEntry
+}
+
+// DeltaEntry holds the new values of A[b] and K[b] for a single bucket b.
+type DeltaEntry struct {
+	Bucket, A, K uint32
+}
+
+// Get the changes to the anchor since a frozen base version was created.
+//
+// The base must have been frozen from this anchor (or a copy of it) at an earlier version.
+func (a *Anchor) Delta(base *FrozenAnchor) (*Delta, error) {
+	if len(base.a) != len(a.A) {
+		return nil, ErrCapacityMismatch
+	}
+	d := &Delta{From: base.v, To: a.v, Capacity: uint32(len(a.A))}
+	for b := range a.A {
+		if a.A[b] != base.a[b] || a.K[b] != base.k[b] {
+			d.Entries = append(d.Entries, DeltaEntry{uint32(b), a.A[b], a.K[b]})
+		}
+	}
+	return d, nil
+}
+
+// Get the changes to the anchor since a frozen base version was created.
+//
+// The base must have been frozen from this anchor (or a copy of it) at an earlier version.
+func (a *CompactAnchor) Delta(base *FrozenCompactAnchor) (*Delta, error) {
+	if len(base.a) != len(a.A) {
+		return nil, ErrCapacityMismatch
+	}
+	d := &Delta{From: base.v, To: a.v, Capacity: uint32(len(a.A))}
+	for b := range a.A {
+		if a.A[b] != base.a[b] || a.K[b] != base.k[b] {
+			d.Entries = append(d.Entries, DeltaEntry{uint32(b), uint32(a.A[b]), uint32(a.K[b])})
+		}
+	}
+	return d, nil
+}
+
+// Apply a delta to a frozen anchor.
+//
+// A new frozen anchor will be returned; the receiver will not be modified. If the delta
+// was not created from the version of the receiver, ErrVersionMismatch will be returned.
+// ErrInvalidDelta will be returned if the delta would leave A and K in a state where some
+// lookups would never end, since deltas may arrive from untrusted sources.
+func (f *FrozenAnchor) Apply(d *Delta) (*FrozenAnchor, error) {
+	if d.From != f.v {
+		return nil, ErrVersionMismatch
+	}
+	if d.Capacity != uint32(len(f.a)) {
+		return nil, ErrCapacityMismatch
+	}
+	for _, e := range d.Entries {
+		if e.Bucket >= d.Capacity || e.A >= d.Capacity || e.K >= d.Capacity {
+			return nil, ErrInvalidDelta
+		}
+	}
+	next := &FrozenAnchor{
+		a: append([]uint32(nil), f.a...),
+		k: append([]uint32(nil), f.k...),
+		v: d.To,
+	}
+	for _, e := range d.Entries {
+		next.a[e.Bucket], next.k[e.Bucket] = e.A, e.K
+	}
+	if !validSuccessors(len(next.a), func(b uint32) (uint32, uint32) { return next.a[b], next.k[b] }) {
+		return nil, ErrInvalidDelta
+	}
+	return next, nil
+}
+
+// Apply a delta to a frozen anchor.
+//
+// A new frozen anchor will be returned; the receiver will not be modified. If the delta
+// was not created from the version of the receiver, ErrVersionMismatch will be returned.
+// See FrozenAnchor.Apply for more information.
+func (f *FrozenCompactAnchor) Apply(d *Delta) (*FrozenCompactAnchor, error) {
+	if d.From != f.v {
+		return nil, ErrVersionMismatch
+	}
+	if d.Capacity != uint32(len(f.a)) {
+		return nil, ErrCapacityMismatch
+	}
+	for _, e := range d.Entries {
+		if e.Bucket >= d.Capacity || e.A >= d.Capacity || e.K >= d.Capacity {
+			return nil, ErrInvalidDelta
+		}
+	}
+	next := &FrozenCompactAnchor{
+		a: make([]uint16, len(f.a)),
+		k: make([]uint16, len(f.k)),
+		v: d.To,
+	}
+	copy(next.a, f.a)
+	copy(next.k, f.k)
+	for _, e := range d.Entries {
+		next.a[e.Bucket], next.k[e.Bucket] = uint16(e.A), uint16(e.K)
+	}
+	if !validSuccessors(len(next.a), func(b uint32) (uint32, uint32) { return uint32(next.a[b]), uint32(next.k[b]) }) {
+		return nil, ErrInvalidDelta
+	}
+	return next, nil
+}
+
+// Encode the delta into a compact binary form.
+//
+// Versions, the capacity and all entries are encoded as unsigned varints. Buckets are
+// encoded as the difference from the previous entry's bucket, so a delta for a single
+// change to an anchor will typically require only a handful of bytes.
+func (d *Delta) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 4*binary.MaxVarintLen64+len(d.Entries)*3*binary.MaxVarintLen32)
+	buf = binary.AppendUvarint(buf, d.From)
+	buf = binary.AppendUvarint(buf, d.To)
+	buf = binary.AppendUvarint(buf, uint64(d.Capacity))
+	buf = binary.AppendUvarint(buf, uint64(len(d.Entries)))
+	prev := uint32(0)
+	for i, e := range d.Entries {
+		if i > 0 && e.Bucket <= prev {
+			return nil, ErrInvalidDelta
+		}
+		buf = binary.AppendUvarint(buf, uint64(e.Bucket-prev))
+		buf = binary.AppendUvarint(buf, uint64(e.A))
+		buf = binary.AppendUvarint(buf, uint64(e.K))
+		prev = e.Bucket
+	}
+	return buf, nil
+}
+
+// Decode a delta from the binary form produced by MarshalBinary.
+func (d *Delta) UnmarshalBinary(data []byte) error {
+	var fields [4]uint64
+	for i := range fields {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return ErrInvalidDelta
+		}
+		fields[i], data = v, data[n:]
+	}
+	from, to, capacity, count := fields[0], fields[1], fields[2], fields[3]
+	if capacity > 1<<32-1 || count > capacity || count*3 > uint64(len(data)) {
+		return ErrInvalidDelta
+	}
+	entries := make([]DeltaEntry, count)
+	prev := uint64(0)
+	for i := range entries {
+		var e [3]uint64
+		for j := range e {
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return ErrInvalidDelta
+			}
+			e[j], data = v, data[n:]
+		}
+		if (i > 0 && e[0] == 0) || prev+e[0] >= capacity || e[1] >= capacity || e[2] >= capacity {
+			return ErrInvalidDelta
+		}
+		prev += e[0]
+		entries[i] = DeltaEntry{uint32(prev), uint32(e[1]), uint32(e[2])}
+	}
+	if len(data) != 0 {
+		return ErrInvalidDelta
+	}
+	d.From, d.To, d.Capacity, d.Entries = from, to, uint32(capacity), entries
+	return nil
+}
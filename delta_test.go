@@ -0,0 +1,144 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDelta(t *testing.T) {
+	const (
+		buckets = 1000
+		used    = 900
+	)
+	a := NewAnchor(buckets, used)
+	client := a.Freeze()
+
+	steps := []func(){
+		func() { a.RemoveBucket(17) },
+		func() { a.RemoveBucket(3); a.RemoveBucket(512) },
+		func() { a.AddBucket() },
+		func() { a.AddBucket(); a.AddBucket(); a.AddBucket() },
+	}
+	for i, step := range steps {
+		base := a.Freeze()
+		step()
+		d, err := a.Delta(base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := d.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Delta
+		if err = decoded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(&decoded, d) {
+			t.Fatalf("step %v: decoded delta = %#+v, delta = %#+v", i, decoded, *d)
+		}
+		t.Logf("step %v: %v entries, %v bytes", i, len(d.Entries), len(data))
+
+		if client, err = client.Apply(&decoded); err != nil {
+			t.Fatalf("step %v: %v", i, err)
+		}
+		if _, err = client.Apply(&decoded); err != ErrVersionMismatch {
+			t.Fatalf("step %v: reapplied delta, err = %v", i, err)
+		}
+		for k := uint64(0); k < 1e4; k++ {
+			if b, cb := a.GetBucket(k), client.GetBucket(k); b != cb {
+				t.Fatalf("step %v: key %v: client bucket = %v, bucket = %v", i, k, cb, b)
+			}
+		}
+	}
+}
+
+func TestDeltaCompact(t *testing.T) {
+	const (
+		buckets = 100
+		used    = 100
+	)
+	a := NewCompactAnchor(buckets, used)
+	base := a.Freeze()
+	a.RemoveBucket(99)
+	a.RemoveBucket(7)
+	a.RemoveBucket(50)
+	a.AddBucket()
+
+	d, err := a.Delta(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := base.Apply(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := uint64(0); k < 1e4; k++ {
+		if b, cb := a.GetBucket(k), client.GetBucket(k); b != cb {
+			t.Fatalf("key %v: client bucket = %v, bucket = %v", k, cb, b)
+		}
+	}
+
+	if _, err = NewCompactAnchor(buckets+1, used).Delta(base); err != ErrCapacityMismatch {
+		t.Fatalf("delta between capacities, err = %v", err)
+	}
+	var invalid Delta
+	if err = invalid.UnmarshalBinary([]byte{0, 1, 10, 1, 10, 0, 0}); err != ErrInvalidDelta {
+		t.Fatalf("decoded out-of-range bucket, err = %v", err)
+	}
+}
+
+func TestDeltaInvalid(t *testing.T) {
+	a := NewAnchor(4, 4)
+	a.RemoveBucket(3)
+	a.RemoveBucket(1) // A[1] = 2, K[1] = 2
+	base := a.Freeze()
+	c, _ := a.ToCompact()
+	compactBase := c.Freeze()
+
+	for name, entries := range map[string][]DeltaEntry{
+		// Working bucket 0 claims a size which no removed bucket may have
+		"size": {{Bucket: 0, A: 1, K: 0}},
+		// Searches through 1 would follow K[1] = 1 forever
+		"successor loop": {{Bucket: 1, A: 2, K: 1}},
+		// Searches through 1 and 3 would alternate forever
+		"successor cycle": {{Bucket: 1, A: 2, K: 3}, {Bucket: 3, A: 3, K: 1}},
+		"all removed":     {{Bucket: 0, A: 1, K: 0}, {Bucket: 2, A: 1, K: 0}},
+	} {
+		d := &Delta{From: base.Version(), To: base.Version() + 1, Capacity: 4, Entries: entries}
+		if _, err := base.Apply(d); err != ErrInvalidDelta {
+			t.Fatalf("%v: err = %v, expected %v", name, err, ErrInvalidDelta)
+		}
+		if _, err := compactBase.Apply(d); err != ErrInvalidDelta {
+			t.Fatalf("%v: compact err = %v, expected %v", name, err, ErrInvalidDelta)
+		}
+	}
+
+	// Successors which are never followed need not be checked
+	d := &Delta{From: base.Version(), To: base.Version() + 1, Capacity: 4, Entries: []DeltaEntry{{Bucket: 3, A: 3, K: 1}}}
+	if _, err := base.Apply(d); err != nil {
+		t.Fatal(err)
+	}
+}
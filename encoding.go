@@ -0,0 +1,250 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrInvalidEncoding is returned when decoding an anchor from malformed data.
+var ErrInvalidEncoding = errors.New("anchor: invalid encoding")
+
+// Each binary encoding begins with a tag identifying the type of anchor which was encoded.
+const (
+	tagAnchor byte = iota + 1
+	tagCompactAnchor
+	tagFrozenAnchor
+	tagFrozenCompactAnchor
+)
+
+// Encode the anchor into a binary form.
+//
+// The encoding includes the version of the anchor, the working set and all removed
+// buckets in removal order, so a decoded anchor will be identical to the encoded anchor.
+func (a *Anchor) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 1+3*binary.MaxVarintLen64+4*(4*len(a.A)+len(a.R)))
+	buf = append(buf, tagAnchor)
+	buf = binary.AppendUvarint(buf, a.v)
+	buf = binary.AppendUvarint(buf, uint64(len(a.A)))
+	buf = binary.AppendUvarint(buf, uint64(a.N))
+	for _, s := range [][]uint32{a.A, a.K, a.W, a.L, a.R} {
+		buf = appendUint32s(buf, s)
+	}
+	return buf, nil
+}
+
+// Decode an anchor from the binary form produced by MarshalBinary.
+//
+// ErrInvalidEncoding will be returned if the data is malformed, or if the decoded state
+// could not have been reached through NewAnchor, AddBucket and RemoveBucket (for example,
+// if no buckets are working, or if some lookups would never end).
+func (a *Anchor) UnmarshalBinary(data []byte) error {
+	v, capacity, data, ok := decodeHeader(tagAnchor, data)
+	n, data, ok2 := readUvarint(data)
+	if !ok || !ok2 || capacity > 1<<32-1 || n > capacity || uint64(len(data)) != 4*(5*capacity-n) {
+		return ErrInvalidEncoding
+	}
+	next := Anchor{
+		A: make([]uint32, capacity),
+		K: make([]uint32, capacity),
+		W: make([]uint32, capacity),
+		L: make([]uint32, capacity),
+		R: make([]uint32, capacity-n, capacity),
+		N: uint32(n),
+		v: v,
+	}
+	for _, s := range [][]uint32{next.A, next.K, next.W, next.L, next.R} {
+		data = decodeUint32s(s, data)
+	}
+	if !next.valid() {
+		return ErrInvalidEncoding
+	}
+	*a = next
+	return nil
+}
+
+// Encode the anchor into a binary form.
+//
+// See Anchor.MarshalBinary for more information.
+func (a *CompactAnchor) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 1+3*binary.MaxVarintLen64+2*(4*len(a.A)+len(a.R)))
+	buf = append(buf, tagCompactAnchor)
+	buf = binary.AppendUvarint(buf, a.v)
+	buf = binary.AppendUvarint(buf, uint64(len(a.A)))
+	buf = binary.AppendUvarint(buf, uint64(a.N))
+	for _, s := range [][]uint16{a.A, a.K, a.W, a.L, a.R} {
+		buf = appendUint16s(buf, s)
+	}
+	return buf, nil
+}
+
+// Decode an anchor from the binary form produced by MarshalBinary.
+//
+// See Anchor.UnmarshalBinary for more information.
+func (a *CompactAnchor) UnmarshalBinary(data []byte) error {
+	v, capacity, data, ok := decodeHeader(tagCompactAnchor, data)
+	n, data, ok2 := readUvarint(data)
+	if !ok || !ok2 || capacity > 1<<16 || n > capacity || uint64(len(data)) != 2*(5*capacity-n) {
+		return ErrInvalidEncoding
+	}
+	next := CompactAnchor{
+		A: make([]uint16, capacity),
+		K: make([]uint16, capacity),
+		W: make([]uint16, capacity),
+		L: make([]uint16, capacity),
+		R: make([]uint16, capacity-n, capacity),
+		N: uint32(n),
+		v: v,
+	}
+	for _, s := range [][]uint16{next.A, next.K, next.W, next.L, next.R} {
+		data = decodeUint16s(s, data)
+	}
+	if !next.valid() {
+		return ErrInvalidEncoding
+	}
+	*a = next
+	return nil
+}
+
+// Encode the frozen anchor into a binary form.
+func (f *FrozenAnchor) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 1+2*binary.MaxVarintLen64+8*len(f.a))
+	buf = append(buf, tagFrozenAnchor)
+	buf = binary.AppendUvarint(buf, f.v)
+	buf = binary.AppendUvarint(buf, uint64(len(f.a)))
+	buf = appendUint32s(buf, f.a)
+	buf = appendUint32s(buf, f.k)
+	return buf, nil
+}
+
+// Decode a frozen anchor from the binary form produced by MarshalBinary.
+//
+// ErrInvalidEncoding will be returned if the data is malformed, or if some lookups in the
+// decoded state would never end.
+func (f *FrozenAnchor) UnmarshalBinary(data []byte) error {
+	v, capacity, data, ok := decodeHeader(tagFrozenAnchor, data)
+	if !ok || capacity > 1<<32-1 || uint64(len(data)) != 8*capacity {
+		return ErrInvalidEncoding
+	}
+	A, K := make([]uint32, capacity), make([]uint32, capacity)
+	data = decodeUint32s(A, data)
+	decodeUint32s(K, data)
+	if !validBuckets(A, capacity) || !validBuckets(K, capacity) ||
+		!validSuccessors(len(A), func(b uint32) (uint32, uint32) { return A[b], K[b] }) {
+		return ErrInvalidEncoding
+	}
+	f.a, f.k, f.v = A, K, v
+	return nil
+}
+
+// Encode the frozen anchor into a binary form.
+func (f *FrozenCompactAnchor) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 1+2*binary.MaxVarintLen64+4*len(f.a))
+	buf = append(buf, tagFrozenCompactAnchor)
+	buf = binary.AppendUvarint(buf, f.v)
+	buf = binary.AppendUvarint(buf, uint64(len(f.a)))
+	buf = appendUint16s(buf, f.a)
+	buf = appendUint16s(buf, f.k)
+	return buf, nil
+}
+
+// Decode a frozen anchor from the binary form produced by MarshalBinary.
+//
+// See FrozenAnchor.UnmarshalBinary for more information.
+func (f *FrozenCompactAnchor) UnmarshalBinary(data []byte) error {
+	v, capacity, data, ok := decodeHeader(tagFrozenCompactAnchor, data)
+	if !ok || capacity > 1<<16 || uint64(len(data)) != 4*capacity {
+		return ErrInvalidEncoding
+	}
+	A, K := make([]uint16, capacity), make([]uint16, capacity)
+	data = decodeUint16s(A, data)
+	decodeUint16s(K, data)
+	if !validBuckets(A, capacity) || !validBuckets(K, capacity) ||
+		!validSuccessors(len(A), func(b uint32) (uint32, uint32) { return uint32(A[b]), uint32(K[b]) }) {
+		return ErrInvalidEncoding
+	}
+	f.a, f.k, f.v = A, K, v
+	return nil
+}
+
+// Decode the tag, version and capacity which begin each encoding.
+func decodeHeader(tag byte, data []byte) (version, capacity uint64, rest []byte, ok bool) {
+	if len(data) == 0 || data[0] != tag {
+		return 0, 0, data, false
+	}
+	if version, data, ok = readUvarint(data[1:]); !ok {
+		return 0, 0, data, false
+	}
+	if capacity, data, ok = readUvarint(data); !ok || capacity == 0 {
+		return 0, 0, data, false
+	}
+	return version, capacity, data, true
+}
+
+func readUvarint(data []byte) (uint64, []byte, bool) {
+	v, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, data, false
+	}
+	return v, data[n:], true
+}
+
+func appendUint32s(buf []byte, s []uint32) []byte {
+	for _, v := range s {
+		buf = binary.LittleEndian.AppendUint32(buf, v)
+	}
+	return buf
+}
+
+func appendUint16s(buf []byte, s []uint16) []byte {
+	for _, v := range s {
+		buf = binary.LittleEndian.AppendUint16(buf, v)
+	}
+	return buf
+}
+
+// Fill dst from the beginning of data and return the remaining data.
+func decodeUint32s(dst []uint32, data []byte) []byte {
+	for i := range dst {
+		dst[i] = binary.LittleEndian.Uint32(data[4*i:])
+	}
+	return data[4*len(dst):]
+}
+
+// Fill dst from the beginning of data and return the remaining data.
+func decodeUint16s(dst []uint16, data []byte) []byte {
+	for i := range dst {
+		dst[i] = binary.LittleEndian.Uint16(data[2*i:])
+	}
+	return data[2*len(dst):]
+}
+
+func validBuckets[T bucket](s []T, capacity uint64) bool {
+	for _, b := range s {
+		if uint64(b) >= capacity {
+			return false
+		}
+	}
+	return true
+}
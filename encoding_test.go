@@ -0,0 +1,136 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncoding(t *testing.T) {
+	a := NewAnchor(100, 90)
+	a.RemoveBucket(12)
+	a.RemoveBucket(40)
+	a.AddBucket()
+	a.RemoveBucket(0)
+
+	data, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Anchor
+	if err = decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, a) {
+		t.Fatalf("decoded = %#+v, anchor = %#+v", decoded, *a)
+	}
+	decoded.AddBucket()
+	a.AddBucket()
+	if !reflect.DeepEqual(&decoded, a) {
+		t.Fatalf("after AddBucket: decoded = %#+v, anchor = %#+v", decoded, *a)
+	}
+	if err = decoded.UnmarshalBinary(data[:len(data)-1]); err != ErrInvalidEncoding {
+		t.Fatalf("decoded truncated data, err = %v", err)
+	}
+
+	f := a.Freeze()
+	if data, err = f.MarshalBinary(); err != nil {
+		t.Fatal(err)
+	}
+	var frozen FrozenAnchor
+	if err = frozen.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&frozen, f) {
+		t.Fatalf("decoded = %#+v, frozen = %#+v", frozen, *f)
+	}
+	if err = decoded.UnmarshalBinary(data); err != ErrInvalidEncoding {
+		t.Fatalf("decoded frozen anchor as anchor, err = %v", err)
+	}
+
+}
+
+func TestEncodingCompact(t *testing.T) {
+	a := NewCompactAnchor(100, 90)
+	a.RemoveBucket(12)
+	a.RemoveBucket(40)
+	a.AddBucket()
+
+	data, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded CompactAnchor
+	if err = decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, a) {
+		t.Fatalf("decoded = %#+v, anchor = %#+v", decoded, *a)
+	}
+
+	f := a.Freeze()
+	if data, err = f.MarshalBinary(); err != nil {
+		t.Fatal(err)
+	}
+	var frozen FrozenCompactAnchor
+	if err = frozen.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&frozen, f) {
+		t.Fatalf("decoded = %#+v, frozen = %#+v", frozen, *f)
+	}
+}
+
+func TestEncodingInvalidState(t *testing.T) {
+	for name, corrupt := range map[string]func(a *Anchor){
+		// Searches through 1 would follow K[1] = 1 forever
+		"successor loop": func(a *Anchor) { a.K[1] = 1 },
+		"no working buckets": func(a *Anchor) {
+			a.R, a.N = append(a.R, 0, 2), 0
+			a.A[0], a.A[2] = 1, 1
+		},
+	} {
+		a := NewAnchor(4, 4)
+		a.RemoveBucket(3)
+		a.RemoveBucket(1)
+		corrupt(a)
+		data, _ := a.MarshalBinary()
+		if err := new(Anchor).UnmarshalBinary(data); err != ErrInvalidEncoding {
+			t.Fatalf("%v: err = %v, expected %v", name, err, ErrInvalidEncoding)
+		}
+		c, _ := a.ToCompact()
+		data, _ = c.MarshalBinary()
+		if err := new(CompactAnchor).UnmarshalBinary(data); err != ErrInvalidEncoding {
+			t.Fatalf("%v: compact err = %v, expected %v", name, err, ErrInvalidEncoding)
+		}
+		data, _ = a.Freeze().MarshalBinary()
+		if err := new(FrozenAnchor).UnmarshalBinary(data); err != ErrInvalidEncoding {
+			t.Fatalf("%v: frozen err = %v, expected %v", name, err, ErrInvalidEncoding)
+		}
+		data, _ = c.Freeze().MarshalBinary()
+		if err := new(FrozenCompactAnchor).UnmarshalBinary(data); err != ErrInvalidEncoding {
+			t.Fatalf("%v: frozen compact err = %v, expected %v", name, err, ErrInvalidEncoding)
+		}
+	}
+}
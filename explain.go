@@ -0,0 +1,145 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Explanation is a trace of the search for the bucket which a key is assigned to.
+type Explanation struct {
+	// Key is the hash-key which was explained.
+	Key uint64
+	// Capacity is the total number of buckets in the anchor.
+	Capacity int
+	// Initial is the first bucket visited, hash(k) mod a.
+	Initial uint32
+	// Steps holds one step for each removed bucket visited, in order.
+	Steps []ExplainStep
+	// Bucket is the working bucket which the key is assigned to.
+	Bucket uint32
+}
+
+// ExplainStep describes how the search for a working bucket continued from a single
+// removed bucket b.
+type ExplainStep struct {
+	// Removed is the removed bucket b.
+	Removed uint32
+	// Size is |Wb|, the number of working buckets just after b was removed (A[b]).
+	Size uint32
+	// Rehash is hb(k) mod |Wb|, the bucket selected by rehashing the key.
+	Rehash uint32
+	// Successors holds each successor followed from Rehash through K, because the bucket
+	// before it was removed prior to b. Successors is empty if Rehash belongs to Wb.
+	Successors []uint32
+	// Next is Wb[Rehash], the next bucket visited.
+	Next uint32
+}
+
+// Explain why a hash-key is assigned to its bucket.
+func (a *Anchor) Explain(key uint64) *Explanation {
+	return explain(key, len(a.A), func(b uint32) (uint32, uint32) { return a.A[b], a.K[b] })
+}
+
+// Explain why a hash-key is assigned to its bucket.
+func (a *CompactAnchor) Explain(key uint64) *Explanation {
+	return explain(key, len(a.A), func(b uint32) (uint32, uint32) { return uint32(a.A[b]), uint32(a.K[b]) })
+}
+
+// Explain why a hash-key is assigned to its bucket.
+func (a *TinyAnchor) Explain(key uint64) *Explanation {
+	return explain(key, len(a.A), func(b uint32) (uint32, uint32) { return uint32(a.A[b]), uint32(a.K[b]) })
+}
+
+// Explain why a hash-key is assigned to its bucket.
+func (t *TableAnchor) Explain(key uint64) *Explanation { return t.a.Explain(key) }
+
+// Explain why a hash-key is assigned to its bucket.
+func (f *FrozenAnchor) Explain(key uint64) *Explanation {
+	return explain(key, len(f.a), func(b uint32) (uint32, uint32) { return f.a[b], f.k[b] })
+}
+
+// Explain why a hash-key is assigned to its bucket.
+func (f *FrozenCompactAnchor) Explain(key uint64) *Explanation {
+	return explain(key, len(f.a), func(b uint32) (uint32, uint32) { return uint32(f.a[b]), uint32(f.k[b]) })
+}
+
+// Follow the same search as GetPath, reading A[b] and K[b] through at.
+func explain(key uint64, capacity int, at func(b uint32) (A, K uint32)) *Explanation {
+	e := &Explanation{Key: key, Capacity: capacity}
+	ha, hb, hc, hd := fleaInit(key)
+	b := fastMod(uint64(hd), uint64(capacity))
+	e.Initial = b
+	for {
+		Ab, _ := at(b)
+		if Ab == 0 {
+			break
+		}
+		ha, hb, hc, hd = fleaRound(ha, hb, hc, hd)
+		h := fastMod(uint64(hd), uint64(Ab))
+		step := ExplainStep{Removed: b, Size: Ab, Rehash: h}
+		for {
+			Ah, Kh := at(h)
+			if Ah < Ab {
+				break
+			}
+			h = Kh
+			step.Successors = append(step.Successors, h)
+		}
+		step.Next = h
+		e.Steps = append(e.Steps, step)
+		b = h
+	}
+	e.Bucket = b
+	return e
+}
+
+// Get the path to the bucket which the key is assigned to, as returned by GetPath.
+func (e *Explanation) Path() []uint32 {
+	path := []uint32{e.Initial}
+	for _, step := range e.Steps {
+		path = append(path, step.Rehash)
+		path = append(path, step.Successors...)
+	}
+	return path
+}
+
+// Describe the search for the bucket which the key is assigned to.
+func (e *Explanation) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "key %v is assigned to bucket %v\n", e.Key, e.Bucket)
+	fmt.Fprintf(&sb, "  hash(k) mod %v selects bucket %v\n", e.Capacity, e.Initial)
+	for _, step := range e.Steps {
+		b := step.Removed
+		fmt.Fprintf(&sb, "  bucket %v is removed; %v buckets were working just after its removal\n", b, step.Size)
+		fmt.Fprintf(&sb, "    rehashing with h%v(k) mod %v selects bucket %v\n", b, step.Size, step.Rehash)
+		prev := step.Rehash
+		for _, next := range step.Successors {
+			fmt.Fprintf(&sb, "    bucket %v was removed before bucket %v, so it is replaced by its successor %v\n", prev, b, next)
+			prev = next
+		}
+	}
+	fmt.Fprintf(&sb, "  bucket %v is working\n", e.Bucket)
+	return sb.String()
+}
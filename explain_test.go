@@ -0,0 +1,71 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	a := NewAnchor(7, 7)
+	// Fig. 2(d), Ex. 13-15
+	a.RemoveBucket(6)
+	a.RemoveBucket(5)
+	a.RemoveBucket(1)
+	a.RemoveBucket(0)
+	f := a.Freeze()
+
+	logged := false
+	for k := uint64(0); k < 1e4; k++ {
+		e := a.Explain(k)
+		if b := a.GetBucket(k); e.Bucket != b {
+			t.Fatalf("key %v: explained bucket = %v, bucket = %v", k, e.Bucket, b)
+		}
+		if path := a.GetPath(k, nil); !reflect.DeepEqual(e.Path(), path) {
+			t.Fatalf("key %v: explained path = %v, path = %v", k, e.Path(), path)
+		}
+		if !reflect.DeepEqual(f.Explain(k), e) {
+			t.Fatalf("key %v: frozen explanation = %#+v, explanation = %#+v", k, *f.Explain(k), *e)
+		}
+		successors := 0
+		for _, step := range e.Steps {
+			if step.Size != a.A[step.Removed] {
+				t.Fatalf("key %v: |W%v| = %v, expected %v", k, step.Removed, step.Size, a.A[step.Removed])
+			}
+			successors += len(step.Successors)
+		}
+		if successors > 0 && !logged {
+			s := e.String()
+			if !strings.Contains(s, "so it is replaced by its successor") {
+				t.Fatalf("key %v: explanation does not mention successors:\n%v", k, s)
+			}
+			t.Logf("\n%v", s)
+			logged = true
+		}
+	}
+	if !logged {
+		t.Fatal("no successors followed")
+	}
+}
@@ -0,0 +1,128 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+// Immutable, lookup-only AnchorHash implementation.
+//
+// Lookups only read A and K; W, L and R are needed solely to add or remove buckets.
+// A frozen anchor retains only A and K, so it requires roughly half the memory of
+// the Anchor it was created from. A frozen anchor is never modified after it has been
+// created, so it is safe for concurrent use by multiple goroutines.
+//
+// A and K are stored in separate arrays, as in Anchor. Lookups which end at the first
+// bucket only read A, which is half the size of an array interleaving A[b] and K[b] for
+// each bucket b; compare BenchmarkGetBucketPacked_* with BenchmarkGetBucket_*.
+type FrozenAnchor struct {
+	// a[b] equals 0 if b is a working bucket, or else the size of the working set just
+	// after the removal of b.
+	a []uint32
+	// k stores the successor for each removed bucket b.
+	k []uint32
+	// v is the version of the anchor at the time it was frozen.
+	v uint64
+}
+
+// Create an immutable, lookup-only copy of the anchor.
+//
+// The frozen anchor will assign every key to the same bucket as the anchor at the time
+// it was frozen. Later changes to the anchor will not affect the frozen anchor.
+func (a *Anchor) Freeze() *FrozenAnchor {
+	return &FrozenAnchor{
+		a: append([]uint32(nil), a.A...),
+		k: append([]uint32(nil), a.K...),
+		v: a.v,
+	}
+}
+
+// Get the bucket which a hash-key is assigned to.
+//
+// See Anchor.GetBucket for more information.
+func (f *FrozenAnchor) GetBucket(key uint64) uint32 {
+	return getBucket(f.a, f.k, key)
+}
+
+// Get the path to the bucket which a hash-key is assigned to.
+//
+// See Anchor.GetPath for more information.
+func (f *FrozenAnchor) GetPath(key uint64, pathBuffer []uint32) []uint32 {
+	return getPath(f.a, f.k, key, pathBuffer)
+}
+
+// Get the version of the anchor at the time it was frozen.
+func (f *FrozenAnchor) Version() uint64 { return f.v }
+
+// Check that every lookup in an anchor with the given A and K will end at a working
+// bucket.
+//
+// The removed buckets must have been removed in descending order of A[b], so their values
+// of A must be exactly N, N+1, ..., a−1, where N is the number of working buckets. A
+// lookup searches for Wb[h] from some h < A[b] for a removed bucket b, and follows K from
+// each visited bucket h with A[h] ≥ A[b]. If each successor followed has a smaller value
+// of A than the bucket it replaced, every search ends. The least A[b] with which each
+// bucket may be visited is tracked from the first removed bucket down, so successors
+// which can never be followed (such as K[b] = b for a bucket which was last in W when it
+// was removed) are not checked.
+func validSuccessors(capacity int, at func(b uint32) (A, K uint32)) bool {
+	n := 0
+	for b := 0; b < capacity; b++ {
+		if A, _ := at(uint32(b)); A == 0 {
+			n++
+		}
+	}
+	if n == 0 {
+		return false
+	}
+	// removed[i] is the bucket b with A[b] = i, for i ≥ N
+	removed, seen := make([]uint32, capacity), make([]bool, capacity)
+	for b := 0; b < capacity; b++ {
+		A, _ := at(uint32(b))
+		if A == 0 {
+			continue
+		}
+		if int(A) < n || int(A) >= capacity || seen[A] {
+			return false
+		}
+		removed[A], seen[A] = uint32(b), true
+	}
+	// reach[b] is the least A[b'] ≥ N with which b may be visited: directly as any
+	// h < A[b'], or through K from a bucket which is visited with that A[b'].
+	reach := make([]int, capacity)
+	for b := range reach {
+		reach[b] = max(b+1, n)
+	}
+	for i := capacity - 1; i >= n; i-- {
+		b := removed[i]
+		if reach[b] > i {
+			continue
+		}
+		_, K := at(b)
+		if int(K) >= capacity {
+			return false
+		}
+		if AK, _ := at(K); AK >= uint32(i) {
+			return false
+		}
+		reach[K] = min(reach[K], reach[b])
+	}
+	return true
+}
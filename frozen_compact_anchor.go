@@ -0,0 +1,68 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+// Immutable, lookup-only compact AnchorHash implementation.
+//
+// See FrozenAnchor and CompactAnchor for more information.
+type FrozenCompactAnchor struct {
+	// a[b] equals 0 if b is a working bucket, or else the size of the working set just
+	// after the removal of b.
+	a []uint16
+	// k stores the successor for each removed bucket b.
+	k []uint16
+	// v is the version of the anchor at the time it was frozen.
+	v uint64
+}
+
+// Create an immutable, lookup-only copy of the anchor.
+//
+// The frozen anchor will assign every key to the same bucket as the anchor at the time
+// it was frozen. Later changes to the anchor will not affect the frozen anchor.
+func (a *CompactAnchor) Freeze() *FrozenCompactAnchor {
+	f := &FrozenCompactAnchor{
+		a: make([]uint16, len(a.A)),
+		k: make([]uint16, len(a.K)),
+		v: a.v,
+	}
+	copy(f.a, a.A)
+	copy(f.k, a.K)
+	return f
+}
+
+// Get the bucket which a hash-key is assigned to.
+//
+// See CompactAnchor.GetBucket for more information.
+func (f *FrozenCompactAnchor) GetBucket(key uint64) uint16 {
+	return getBucket(f.a, f.k, key)
+}
+
+// Get the path to the bucket which a hash-key is assigned to.
+//
+// See CompactAnchor.GetPath for more information.
+func (f *FrozenCompactAnchor) GetPath(key uint64, pathBuffer []uint16) []uint16 {
+	return getPath(f.a, f.k, key, pathBuffer)
+}
+
+// Get the version of the anchor at the time it was frozen.
+func (f *FrozenCompactAnchor) Version() uint64 { return f.v }
@@ -0,0 +1,74 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	const (
+		buckets = 100
+		used    = 80
+	)
+	a := NewAnchor(buckets, used)
+	a.RemoveBucket(17)
+	a.RemoveBucket(3)
+	a.RemoveBucket(42)
+	f := a.Freeze()
+
+	c := NewCompactAnchor(buckets, used)
+	c.RemoveBucket(17)
+	c.RemoveBucket(3)
+	c.RemoveBucket(42)
+	fc := c.Freeze()
+
+	path, frozenPath := make([]uint32, 0, 64), make([]uint32, 0, 64)
+	for i := uint64(0); i < 1e5; i++ {
+		b := a.GetBucket(i)
+		if fb := f.GetBucket(i); fb != b {
+			t.Fatalf("key %v: frozen bucket = %v, bucket = %v", i, fb, b)
+		}
+		if fb := fc.GetBucket(i); uint32(fb) != b {
+			t.Fatalf("key %v: frozen compact bucket = %v, bucket = %v", i, fb, b)
+		}
+		path, frozenPath = a.GetPath(i, path[:0]), f.GetPath(i, frozenPath[:0])
+		if !reflect.DeepEqual(path, frozenPath) {
+			t.Fatalf("key %v: frozen path = %v, path = %v", i, frozenPath, path)
+		}
+	}
+
+	// Later changes must not affect the frozen anchor
+	before := make([]uint32, 1e4)
+	for i := range before {
+		before[i] = f.GetBucket(uint64(i))
+	}
+	a.AddBucket()
+	a.RemoveBucket(0)
+	for i := range before {
+		if b := f.GetBucket(uint64(i)); b != before[i] {
+			t.Fatalf("key %v: frozen bucket changed from %v to %v", i, before[i], b)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"reflect"
+	"testing"
+)
+
+// A naive model of the working set and removal order of an anchor.
+type model struct {
+	working []bool
+	// sizes[b] is the size of the working set just after the removal of b.
+	sizes []uint32
+	// r holds removed buckets in removal order.
+	r []uint32
+	n uint32
+}
+
+func newModel(buckets, used int) *model {
+	m := &model{working: make([]bool, buckets), sizes: make([]uint32, buckets), n: uint32(used)}
+	for b := 0; b < used; b++ {
+		m.working[b] = true
+	}
+	for b := buckets - 1; b >= used; b-- {
+		m.r, m.sizes[b] = append(m.r, uint32(b)), uint32(b)
+	}
+	return m
+}
+
+func (m *model) add() uint32 {
+	b := m.r[len(m.r)-1]
+	m.r, m.working[b], m.sizes[b] = m.r[:len(m.r)-1], true, 0
+	m.n++
+	return b
+}
+
+func (m *model) remove(b uint32) {
+	if int(b) >= len(m.working) || !m.working[b] || m.n == 1 {
+		return
+	}
+	m.n--
+	m.r, m.working[b], m.sizes[b] = append(m.r, b), false, m.n
+}
+
+// Decode a sequence of operations from fuzzer input and apply them to each anchor type, the
+// model and the reference implementation, checking invariants and routing after every step.
+//
+// The first two bytes select the capacity and initial size. Each following pair of bytes
+// selects an operation and an arbitrary bucket, which may be out of range.
+func fuzzOps(t *testing.T, data []byte) {
+	if len(data) < 2 {
+		return
+	}
+	buckets := 1 + int(data[0])%64
+	used := 1 + int(data[1])%buckets
+	data = data[2:]
+
+	m := newModel(buckets, used)
+	a := NewAnchor(buckets, used)
+	c := NewCompactAnchor(buckets, used)
+	ta := NewTableAnchor(buckets, used)
+	ti := NewTinyAnchor(buckets, used)
+	ref := newRefAnchor(buckets, used)
+	check := func(step int) {
+		t.Helper()
+		checkInvariants(t, step, m, a.A, a.K, a.W, a.L, a.R, a.N)
+		checkInvariants(t, step, m, widen(c.A), widen(c.K), widen(c.W), widen(c.L), widen(c.R), c.N)
+		checkInvariants(t, step, m, widen8(ti.A), widen8(ti.K), widen8(ti.W), widen8(ti.L), widen8(ti.R), uint32(ti.N))
+		if !a.valid() || !c.valid() {
+			t.Fatalf("step %v: reachable state rejected as invalid", step)
+		}
+		var path []uint32
+		for k := uint64(0); k < 64; k++ {
+			key := k * 0x9e3779b97f4a7c15
+			b := a.GetBucket(key)
+			if !m.working[b] {
+				t.Fatalf("step %v: key %v assigned to removed bucket %v", step, key, b)
+			}
+			if rb := ref.getBucket(key); rb != b {
+				t.Fatalf("step %v: key %v: bucket = %v, reference bucket = %v", step, key, b, rb)
+			}
+			if cb, tb, tib := uint32(c.GetBucket(key)), uint32(ta.GetBucket(key)), uint32(ti.GetBucket(key)); cb != b || tb != b || tib != b {
+				t.Fatalf("step %v: key %v: bucket = %v, compact bucket = %v, table bucket = %v, tiny bucket = %v", step, key, b, cb, tb, tib)
+			}
+			if path = a.GetPath(key, path[:0]); !reflect.DeepEqual(path, ref.getPath(key)) {
+				t.Fatalf("step %v: key %v: path = %v, reference path = %v", step, key, path, ref.getPath(key))
+			}
+		}
+	}
+
+	check(0)
+	for step := 1; len(data) >= 2; step, data = step+1, data[2:] {
+		op, b := data[0], uint32(data[1])
+		if op%2 == 0 && len(m.r) > 0 {
+			mb := m.add()
+			ref.add()
+			ab, cb, tb, tib := a.AddBucket(), uint32(c.AddBucket()), uint32(ta.AddBucket()), uint32(ti.AddBucket())
+			if ab != mb || cb != mb || tb != mb || tib != mb {
+				t.Fatalf("step %v: added %v, compact added %v, table added %v, tiny added %v, expected %v", step, ab, cb, tb, tib, mb)
+			}
+		} else {
+			m.remove(b)
+			ref.remove(b)
+			a.RemoveBucket(b)
+			if b <= 0xFFFF {
+				c.RemoveBucket(uint16(b))
+				ta.RemoveBucket(uint16(b))
+			}
+			if b <= 0xFF {
+				ti.RemoveBucket(uint8(b))
+			}
+		}
+		check(step)
+	}
+}
+
+func checkInvariants(t *testing.T, step int, m *model, A, K, W, L, R []uint32, N uint32) {
+	t.Helper()
+	if N != m.n {
+		t.Fatalf("step %v: N = %v, expected %v", step, N, m.n)
+	}
+	if !reflect.DeepEqual(R, m.r) && (len(R) > 0 || len(m.r) > 0) {
+		t.Fatalf("step %v: R = %v, expected %v", step, R, m.r)
+	}
+	for b := range A {
+		if A[b] != m.sizes[b] {
+			t.Fatalf("step %v: A[%v] = %v, expected %v", step, b, A[b], m.sizes[b])
+		}
+		if m.working[b] && K[b] != uint32(b) {
+			t.Fatalf("step %v: K[%v] = %v for working bucket", step, b, K[b])
+		}
+	}
+	seen := make([]bool, len(A))
+	for i, b := range W[:N] {
+		if !m.working[b] || seen[b] {
+			t.Fatalf("step %v: W = %v contains a removed or repeated bucket %v", step, W[:N], b)
+		}
+		if L[b] != uint32(i) {
+			t.Fatalf("step %v: L[%v] = %v, expected %v", step, b, L[b], i)
+		}
+		seen[b] = true
+	}
+}
+
+func widen(s []uint16) []uint32 {
+	w := make([]uint32, len(s))
+	for i, v := range s {
+		w[i] = uint32(v)
+	}
+	return w
+}
+
+func widen8(s []uint8) []uint32 {
+	w := make([]uint32, len(s))
+	for i, v := range s {
+		w[i] = uint32(v)
+	}
+	return w
+}
+
+func FuzzAnchor(f *testing.F) {
+	f.Add([]byte{6, 6, 1, 6, 1, 5, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	f.Add([]byte{9, 4, 0, 0, 0, 0, 1, 3, 1, 0, 1, 1, 0, 0, 0, 0})
+	f.Add([]byte{63, 31, 1, 200, 1, 17, 1, 17, 0, 0, 1, 3, 1, 2, 1, 1, 1, 0})
+	f.Add([]byte{1, 0, 1, 0, 0, 0})
+	f.Fuzz(fuzzOps)
+}
@@ -0,0 +1,239 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// Golden vectors pin the bucket and path for a set of keys after a sequence of changes,
+// so any change to placement between versions will be detected.
+//
+// To add vectors, append a case to goldenCases and run:
+//
+//	go test -run TestGolden -golden.update
+//
+// Vectors which already exist in the golden file will be checked but never rewritten.
+var updateGolden = flag.Bool("golden.update", false, "add missing vectors to "+goldenFile)
+
+const goldenFile = "testdata/golden.json"
+
+type goldenVector struct {
+	Name     string       `json:"name"`
+	Capacity int          `json:"capacity"`
+	Used     int          `json:"used"`
+	Ops      []goldenOp   `json:"ops"`
+	Keys     []goldenPath `json:"keys"`
+}
+
+type goldenOp struct {
+	// Op is either "add" or "remove".
+	Op     string `json:"op"`
+	Bucket uint32 `json:"bucket"`
+}
+
+type goldenPath struct {
+	Key    uint64   `json:"key"`
+	Bucket uint32   `json:"bucket"`
+	Path   []uint32 `json:"path"`
+}
+
+type goldenCase struct {
+	name           string
+	capacity, used int
+	// ops will be generated from a seeded random source when the vector is created; the
+	// generated operations are stored in the golden file.
+	ops func(rng *rand.Rand, a *Anchor) []goldenOp
+}
+
+var goldenCases = []goldenCase{
+	{name: "full-10", capacity: 10, used: 10},
+	{name: "half-10", capacity: 10, used: 5},
+	{name: "paper-fig2", capacity: 7, used: 7, ops: removeOps(6, 5, 1, 0)},
+	{name: "restored-7", capacity: 7, used: 7, ops: func(rng *rand.Rand, a *Anchor) []goldenOp {
+		return append(removeOps(6, 5, 1, 0)(rng, a), addOps(4)...)
+	}},
+	{name: "churn-100", capacity: 100, used: 80, ops: randomOps(200)},
+	{name: "churn-1000", capacity: 1000, used: 1000, ops: randomOps(500)},
+	{name: "churn-1m", capacity: 1000000, used: 900000, ops: randomOps(100)},
+	{name: "compact-max", capacity: 1 << 16, used: 1 << 16, ops: randomOps(100)},
+}
+
+func removeOps(buckets ...uint32) func(*rand.Rand, *Anchor) []goldenOp {
+	return func(*rand.Rand, *Anchor) []goldenOp {
+		ops := make([]goldenOp, len(buckets))
+		for i, b := range buckets {
+			ops[i] = goldenOp{"remove", b}
+		}
+		return ops
+	}
+}
+
+// Added buckets will be filled in when the vector is created.
+func addOps(n int) []goldenOp {
+	ops := make([]goldenOp, n)
+	for i := range ops {
+		ops[i].Op = "add"
+	}
+	return ops
+}
+
+func randomOps(n int) func(*rand.Rand, *Anchor) []goldenOp {
+	return func(rng *rand.Rand, a *Anchor) []goldenOp {
+		a = a.clone()
+		ops := make([]goldenOp, n)
+		for i := range ops {
+			if len(a.R) > 0 && rng.Intn(3) == 0 {
+				ops[i] = goldenOp{"add", a.AddBucket()}
+			} else {
+				b := a.W[rng.Intn(int(a.N))]
+				a.RemoveBucket(b)
+				ops[i] = goldenOp{"remove", b}
+			}
+		}
+		return ops
+	}
+}
+
+func goldenKeys() []uint64 {
+	keys := make([]uint64, 0, 80)
+	for k := uint64(0); k < 16; k++ {
+		keys = append(keys, k)
+	}
+	for k := uint64(1); k <= 64; k++ {
+		keys = append(keys, k*0x9e3779b97f4a7c15)
+	}
+	return keys
+}
+
+func newGoldenVector(c goldenCase) goldenVector {
+	v := goldenVector{Name: c.name, Capacity: c.capacity, Used: c.used, Ops: []goldenOp{}}
+	a := NewAnchor(c.capacity, c.used)
+	if c.ops != nil {
+		v.Ops = c.ops(rand.New(rand.NewSource(1)), a)
+	}
+	for i, op := range v.Ops {
+		if op.Op == "add" {
+			v.Ops[i].Bucket = a.AddBucket()
+		} else {
+			a.RemoveBucket(op.Bucket)
+		}
+	}
+	for _, key := range goldenKeys() {
+		v.Keys = append(v.Keys, goldenPath{key, a.GetBucket(key), a.GetPath(key, nil)})
+	}
+	return v
+}
+
+func TestGolden(t *testing.T) {
+	var vectors []goldenVector
+	data, err := os.ReadFile(goldenFile)
+	if err != nil && !(*updateGolden && os.IsNotExist(err)) {
+		t.Fatal(err)
+	}
+	if err == nil {
+		if err = json.Unmarshal(data, &vectors); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, v := range vectors {
+		checkGoldenVector(t, v)
+	}
+
+	if !*updateGolden {
+		return
+	}
+	existing := make(map[string]bool)
+	for _, v := range vectors {
+		existing[v.Name] = true
+	}
+	added := 0
+	for _, c := range goldenCases {
+		if !existing[c.name] {
+			vectors = append(vectors, newGoldenVector(c))
+			added++
+		}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "\t")
+	if err = enc.Encode(vectors); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(goldenFile, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("added %v vectors to %v", added, goldenFile)
+}
+
+func checkGoldenVector(t *testing.T, v goldenVector) {
+	t.Helper()
+	a := NewAnchor(v.Capacity, v.Used)
+	var c *CompactAnchor
+	if v.Capacity <= 1<<16 {
+		c = NewCompactAnchor(v.Capacity, v.Used)
+	}
+	for i, op := range v.Ops {
+		switch op.Op {
+		case "add":
+			if b := a.AddBucket(); b != op.Bucket {
+				t.Fatalf("%v: op %v: added bucket %v, expected %v", v.Name, i, b, op.Bucket)
+			}
+			if c != nil {
+				c.AddBucket()
+			}
+		case "remove":
+			a.RemoveBucket(op.Bucket)
+			if c != nil {
+				c.RemoveBucket(uint16(op.Bucket))
+			}
+		default:
+			t.Fatalf("%v: op %v: unknown op %q", v.Name, i, op.Op)
+		}
+	}
+	var compactPath []uint16
+	for _, k := range v.Keys {
+		if b := a.GetBucket(k.Key); b != k.Bucket {
+			t.Fatalf("%v: key %v: bucket = %v, expected %v", v.Name, k.Key, b, k.Bucket)
+		}
+		if path := a.GetPath(k.Key, nil); !reflect.DeepEqual(path, k.Path) {
+			t.Fatalf("%v: key %v: path = %v, expected %v", v.Name, k.Key, path, k.Path)
+		}
+		if c == nil {
+			continue
+		}
+		if b := c.GetBucket(k.Key); uint32(b) != k.Bucket {
+			t.Fatalf("%v: key %v: compact bucket = %v, expected %v", v.Name, k.Key, b, k.Bucket)
+		}
+		if compactPath = c.GetPath(k.Key, compactPath[:0]); !reflect.DeepEqual(widen(compactPath), k.Path) {
+			t.Fatalf("%v: key %v: compact path = %v, expected %v", v.Name, k.Key, compactPath, k.Path)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"errors"
+	"iter"
+)
+
+// ErrInvalidState is returned when an anchor which violates the invariants of AnchorHash
+// is given where a valid anchor is required.
+var ErrInvalidState = errors.New("anchor: invalid state")
+
+// AnchorHash implementation with private state.
+//
+// Hash behaves exactly as Anchor, but its state may only be changed through its methods,
+// so the invariants of AnchorHash always hold. The internal arrays may be inspected
+// through read-only views. Hash is not safe for concurrent use; see Freeze for
+// lookup-only copies which are.
+type Hash struct {
+	a Anchor
+}
+
+// Create a new hash with a given capacity and initial size.
+//
+// Buckets 0 through used-1 will be working. See NewAnchor for more information.
+func New(buckets, used int) *Hash { return &Hash{a: *NewAnchor(buckets, used)} }
+
+// Create a new hash from a copy of the state of an anchor.
+//
+// ErrInvalidState will be returned if the anchor is not in a state reachable through
+// NewAnchor, AddBucket and RemoveBucket. Later changes to the anchor will not affect
+// the hash.
+func FromAnchor(a *Anchor) (*Hash, error) {
+	if !a.valid() {
+		return nil, ErrInvalidState
+	}
+	return &Hash{a: *a.clone()}, nil
+}
+
+// Get a copy of the state of the hash as an anchor. Changes to the anchor will not
+// affect the hash.
+func (h *Hash) Anchor() *Anchor { return h.a.clone() }
+
+// Get the bucket which a hash-key is assigned to.
+//
+// See Anchor.GetBucket for more information.
+func (h *Hash) GetBucket(key uint64) uint32 { return h.a.GetBucket(key) }
+
+// Get the path to the bucket which a hash-key is assigned to.
+//
+// See Anchor.GetPath for more information.
+func (h *Hash) GetPath(key uint64, pathBuffer []uint32) []uint32 {
+	return h.a.GetPath(key, pathBuffer)
+}
+
+// Get the buckets which a slice of hash-keys are assigned to.
+//
+// See Anchor.GetBuckets for more information.
+func (h *Hash) GetBuckets(keys []uint64, out []uint32) { h.a.GetBuckets(keys, out) }
+
+// Add a bucket to the hash and return it.
+//
+// See Anchor.AddBucket for more information.
+func (h *Hash) AddBucket() uint32 { return h.a.AddBucket() }
+
+// Remove a bucket from the hash.
+//
+// See Anchor.RemoveBucket for more information.
+func (h *Hash) RemoveBucket(b uint32) { h.a.RemoveBucket(b) }
+
+// Get the version of the hash.
+//
+// See Anchor.Version for more information.
+func (h *Hash) Version() uint64 { return h.a.v }
+
+// Get the number of working buckets.
+func (h *Hash) Len() int { return int(h.a.N) }
+
+// Get the total number of buckets.
+func (h *Hash) Capacity() int { return len(h.a.A) }
+
+// Get the number of removed buckets.
+func (h *Hash) Removed() int { return len(h.a.R) }
+
+// Check if b is a working bucket.
+func (h *Hash) IsWorking(b uint32) bool { return h.a.IsWorking(b) }
+
+// Iterate over the working buckets in their desired order.
+//
+// The hash must not be modified during iteration.
+func (h *Hash) WorkingBuckets() iter.Seq[uint32] { return h.a.WorkingBuckets() }
+
+// Get a copy of the removed buckets in the order in which they were removed.
+func (h *Hash) RemovedBuckets() []uint32 { return h.a.RemovedBuckets() }
+
+// Get the approximate number of bytes of memory retained by the hash.
+func (h *Hash) MemoryBytes() int { return h.a.MemoryBytes() }
+
+// Create an immutable, lookup-only copy of the hash.
+//
+// See Anchor.Freeze for more information.
+func (h *Hash) Freeze() *FrozenAnchor { return h.a.Freeze() }
+
+// Get the changes to A and K since a frozen copy of the hash was created.
+//
+// See Anchor.Delta for more information.
+func (h *Hash) Delta(base *FrozenAnchor) (*Delta, error) { return h.a.Delta(base) }
+
+// Explain why a hash-key is assigned to its bucket.
+//
+// See Anchor.Explain for more information.
+func (h *Hash) Explain(key uint64) *Explanation { return h.a.Explain(key) }
+
+// Compute distribution and path-length statistics over a sample of hash-keys.
+//
+// See Anchor.Stats for more information.
+func (h *Hash) Stats(sampleKeys []uint64) *Stats { return h.a.Stats(sampleKeys) }
+
+// Convert the hash to a compact hash.
+//
+// See Anchor.ToCompact for more information.
+func (h *Hash) ToCompact() (*CompactHash, error) {
+	c, err := h.a.ToCompact()
+	if err != nil {
+		return nil, err
+	}
+	return &CompactHash{a: *c}, nil
+}
+
+// Encode the hash into a binary form, which is identical to the encoding of an Anchor.
+func (h *Hash) MarshalBinary() ([]byte, error) { return h.a.MarshalBinary() }
+
+// Decode a hash from the binary form produced by MarshalBinary.
+//
+// See Anchor.UnmarshalBinary for more information.
+func (h *Hash) UnmarshalBinary(data []byte) error { return h.a.UnmarshalBinary(data) }
+
+// Apply a batch of operations to the hash, or none of them.
+//
+// See Anchor.ApplyBatch for more information.
+func (h *Hash) ApplyBatch(ops []Op) error { return h.a.ApplyBatch(ops) }
+
+// Get a read-only view of A, which holds |Wb| for each removed bucket b, or else 0.
+func (h *Hash) A() View { return View{h.a.A} }
+
+// Get a read-only view of K, which holds the successor of each removed bucket.
+func (h *Hash) K() View { return View{h.a.K} }
+
+// Get a read-only view of W, which holds the working buckets in their desired order.
+func (h *Hash) W() View { return View{h.a.W[:h.a.N]} }
+
+// Get a read-only view of L, which holds the most recent location of each bucket within W.
+func (h *Hash) L() View { return View{h.a.L} }
+
+// Get a read-only view of R, which holds the removed buckets in removal order.
+func (h *Hash) R() View { return View{h.a.R} }
+
+// Read-only view of an array within a Hash.
+//
+// A view shares memory with the hash it was obtained from, so it must not be used after
+// the hash has been modified.
+type View struct {
+	s []uint32
+}
+
+// Get the length of the array.
+func (v View) Len() int { return len(v.s) }
+
+// Get the element at index i. At panics if i is out of range.
+func (v View) At(i int) uint32 { return v.s[i] }
+
+// Iterate over the indices and elements of the array.
+func (v View) All() iter.Seq2[int, uint32] {
+	return func(yield func(int, uint32) bool) {
+		for i, x := range v.s {
+			if !yield(i, x) {
+				return
+			}
+		}
+	}
+}
+
+// Append the elements of the array to dst and return the extended slice.
+func (v View) AppendTo(dst []uint32) []uint32 { return append(dst, v.s...) }
+
+// Create a deep copy of the anchor.
+func (a *Anchor) clone() *Anchor {
+	R := make([]uint32, len(a.R), len(a.A))
+	copy(R, a.R)
+	return &Anchor{
+		A: append([]uint32(nil), a.A...),
+		K: append([]uint32(nil), a.K...),
+		W: append([]uint32(nil), a.W...),
+		L: append([]uint32(nil), a.L...),
+		R: R,
+		N: a.N,
+		v: a.v,
+	}
+}
+
+// Check if the anchor is in a state reachable through NewAnchor, AddBucket and
+// RemoveBucket.
+func (a *Anchor) valid() bool { return validAnchor(a.A, a.K, a.W, a.L, a.R, a.N) }
+
+// Check if an anchor with capacity a is in a state reachable through AddBucket and
+// RemoveBucket. A valid anchor satisfies each of the following:
+//
+//	1 ≤ N ≤ a and |R| = a−N
+//	A[R[i]] = a−1−i for each index i of R        ◃ |Wb| just after each removal
+//	A[b] = 0 and K[b] = b for each working bucket b
+//	W[0..N−1] holds each working bucket once, with L[W[i]] = i
+//	every search through K ends                   ◃ see validSuccessors
+//
+// W[N..a−1] and L for removed buckets are not read by GETBUCKET, but ADDBUCKET restores
+// the working set from them, so each addition is replayed from R on copies of W and L.
+// Just before b is added with N working buckets, REMOVEBUCKET(b) must have left its
+// replacement K[b] in both W[N] and W[L[b]], where L[b] ≤ N, and L[b] = N only if
+// K[b] = b; each addition then preserves the invariants for W[0..N] and L.
+func validAnchor[T bucket](A, K, W, L, R []T, N uint32) bool {
+	capacity := uint64(len(A))
+	if N == 0 || uint64(N) > capacity || len(K) != len(A) || len(W) != len(A) ||
+		len(L) != len(A) || uint64(len(R))+uint64(N) != capacity {
+		return false
+	}
+	if !validBuckets(K, capacity) || !validBuckets(W, capacity) ||
+		!validBuckets(L, capacity) || !validBuckets(R, capacity) {
+		return false
+	}
+	removed := make([]bool, len(A))
+	for i, b := range R {
+		if removed[b] || uint64(A[b]) != capacity-1-uint64(i) {
+			return false
+		}
+		removed[b] = true
+	}
+	for b := range A {
+		if !removed[b] && (A[b] != 0 || K[b] != T(b)) {
+			return false
+		}
+	}
+	seen := make([]bool, len(A))
+	for i, b := range W[:N] {
+		if removed[b] || seen[b] || int(L[b]) != i {
+			return false
+		}
+		seen[b] = true
+	}
+	w, l := append([]T(nil), W...), append([]T(nil), L...)
+	for i, n := len(R)-1, N; i >= 0; i, n = i-1, n+1 {
+		b := R[i]
+		if uint32(l[b]) > n || w[n] != K[b] || w[l[b]] != K[b] || (uint32(l[b]) == n && K[b] != b) {
+			return false
+		}
+		l[w[n]] = T(n)
+		w[l[b]] = b
+	}
+	return validSuccessors(len(A), func(b uint32) (uint32, uint32) { return uint32(A[b]), uint32(K[b]) })
+}
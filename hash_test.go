@@ -0,0 +1,194 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	a, h := NewAnchor(100, 60), New(100, 60)
+	c, hc := NewCompactAnchor(100, 60), NewCompact(100, 60)
+	var path, hashPath []uint32
+	var compactPath, compactHashPath []uint16
+	for i := 0; i < 200; i++ {
+		if len(a.R) > 0 && rng.Intn(2) == 0 {
+			if b, hb := a.AddBucket(), h.AddBucket(); b != hb {
+				t.Fatalf("hash added %v, anchor added %v", hb, b)
+			}
+			c.AddBucket()
+			hc.AddBucket()
+		} else {
+			b := uint32(rng.Intn(100))
+			a.RemoveBucket(b)
+			h.RemoveBucket(b)
+			c.RemoveBucket(uint16(b))
+			hc.RemoveBucket(uint16(b))
+		}
+		if h.Version() != a.Version() || hc.Version() != c.Version() {
+			t.Fatalf("hash version = %v, compact hash version = %v, expected %v", h.Version(), hc.Version(), a.Version())
+		}
+		if !reflect.DeepEqual(h.Anchor(), a) || !reflect.DeepEqual(hc.CompactAnchor(), c) {
+			t.Fatalf("step %v: hash state differs from anchor state", i)
+		}
+		for k := uint64(0); k < 100; k++ {
+			path, hashPath = a.GetPath(k, path[:0]), h.GetPath(k, hashPath[:0])
+			if !slices.Equal(path, hashPath) {
+				t.Fatalf("key %v: hash path = %v, anchor path = %v", k, hashPath, path)
+			}
+			compactPath, compactHashPath = c.GetPath(k, compactPath[:0]), hc.GetPath(k, compactHashPath[:0])
+			if !slices.Equal(compactPath, compactHashPath) {
+				t.Fatalf("key %v: compact hash path = %v, compact anchor path = %v", k, compactHashPath, compactPath)
+			}
+		}
+	}
+}
+
+func TestHashViews(t *testing.T) {
+	h := New(10, 8)
+	h.RemoveBucket(3)
+	h.RemoveBucket(0)
+	a := h.Anchor()
+	for _, v := range []struct {
+		name string
+		view View
+		s    []uint32
+	}{
+		{"A", h.A(), a.A},
+		{"K", h.K(), a.K},
+		{"W", h.W(), a.W[:a.N]},
+		{"L", h.L(), a.L},
+		{"R", h.R(), a.R},
+	} {
+		if got := v.view.AppendTo(nil); !slices.Equal(got, v.s) {
+			t.Fatalf("%v = %v, expected %v", v.name, got, v.s)
+		}
+		if v.view.Len() != len(v.s) {
+			t.Fatalf("len(%v) = %v, expected %v", v.name, v.view.Len(), len(v.s))
+		}
+		for i, x := range v.view.All() {
+			if x != v.s[i] || v.view.At(i) != x {
+				t.Fatalf("%v[%v] = %v, expected %v", v.name, i, x, v.s[i])
+			}
+		}
+	}
+
+	// Copies of the state must not share memory with the hash
+	a.A[0], a.K[0] = 0, 0
+	s := h.A().AppendTo(nil)
+	s[0] = 0
+	if h.A().At(0) == 0 || h.K().At(0) == 0 {
+		t.Fatalf("hash modified through a copy of its state")
+	}
+
+	hc := NewCompact(10, 8)
+	hc.RemoveBucket(3)
+	if r := hc.R().AppendTo(nil); !slices.Equal(r, []uint16{9, 8, 3}) {
+		t.Fatalf("compact R = %v, expected [9 8 3]", r)
+	}
+}
+
+func TestFromAnchor(t *testing.T) {
+	a := NewAnchor(4, 4)
+	a.RemoveBucket(3)
+	a.RemoveBucket(1)
+	h, err := FromAnchor(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.AddBucket()
+	if h.IsWorking(1) || h.Version() != 2 {
+		t.Fatalf("hash modified through the anchor it was created from")
+	}
+	c, _ := a.ToCompact()
+	if _, err := FromCompactAnchor(c); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, corrupt := range map[string]func(a *Anchor){
+		"working bucket in A": func(a *Anchor) { a.A[0] = 3 },
+		"successor loop":      func(a *Anchor) { a.K[1] = 1 },
+		"successor cycle":     func(a *Anchor) { a.K[1], a.K[2] = 2, 1 },
+		"N":                   func(a *Anchor) { a.N++ },
+		"W":                   func(a *Anchor) { a.W[0], a.W[1] = a.W[1], a.W[0] },
+		"R":                   func(a *Anchor) { a.R[0], a.R[1] = a.R[1], a.R[0] },
+		"out of range":        func(a *Anchor) { a.K[3] = 4 },
+		"removed W and L":     func(a *Anchor) { a.W[2], a.W[3], a.L[1], a.L[3] = 0, 0, 0, 0 },
+		"removed W":           func(a *Anchor) { a.W[3] = 0 },
+		"removed L":           func(a *Anchor) { a.L[3] = 2 },
+	} {
+		a := NewAnchor(4, 4)
+		a.RemoveBucket(3)
+		a.RemoveBucket(1)
+		corrupt(a)
+		if _, err := FromAnchor(a); !errors.Is(err, ErrInvalidState) {
+			t.Fatalf("%v: err = %v, expected %v", name, err, ErrInvalidState)
+		}
+		data, _ := a.MarshalBinary()
+		if err := new(Hash).UnmarshalBinary(data); err != ErrInvalidEncoding {
+			t.Fatalf("%v: decoding err = %v, expected %v", name, err, ErrInvalidEncoding)
+		}
+		if c, err := a.ToCompact(); err == nil {
+			if _, err := FromCompactAnchor(c); !errors.Is(err, ErrInvalidState) {
+				t.Fatalf("%v: compact err = %v, expected %v", name, err, ErrInvalidState)
+			}
+		}
+	}
+}
+
+func TestHashEncoding(t *testing.T) {
+	h := New(50, 40)
+	h.RemoveBucket(7)
+	h.RemoveBucket(21)
+	data, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Hash
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Anchor(), h.Anchor()) {
+		t.Fatalf("decoded hash differs from encoded hash")
+	}
+
+	hc, err := h.ToCompact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err = hc.MarshalBinary(); err != nil {
+		t.Fatal(err)
+	}
+	var decodedCompact CompactHash
+	if err := decodedCompact.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decodedCompact.ToHash().Anchor(), h.Anchor()) {
+		t.Fatalf("decoded compact hash differs from encoded hash")
+	}
+}
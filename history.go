@@ -0,0 +1,160 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import "errors"
+
+var (
+	// ErrUndo is returned when undoing more operations than a history holds.
+	ErrUndo = errors.New("anchor: not enough operations to undo")
+	// ErrTxnOpen is returned when beginning a transaction or undoing operations while a
+	// transaction is open.
+	ErrTxnOpen = errors.New("anchor: transaction is open")
+)
+
+// History records the changes made to an anchor, so they may be undone.
+//
+// Changes are made through transactions, which are either committed to the history or
+// rolled back. Undoing or rolling back a change returns the anchor to exactly the state
+// it was in before the change, so every key is assigned to the same bucket as before,
+// even when other buckets were removed or added after the change. Only the version of
+// the anchor differs, since it is incremented by every change.
+//
+// Only one transaction may be open at a time. Operations may not be undone while a
+// transaction is open.
+type History struct {
+	a   *Anchor
+	ops []Op
+	// txn is the open transaction, or nil.
+	txn *Txn
+}
+
+// A transaction groups changes to an anchor which are committed or rolled back together.
+type Txn struct {
+	h      *History
+	ops    []Op
+	closed bool
+}
+
+// Create a new, empty history for changes to a copy of an anchor.
+func NewHistory(a *Anchor) *History { return &History{a: a.clone()} }
+
+// Get a copy of the anchor which the history records changes to.
+func (h *History) Anchor() *Anchor { return h.a.clone() }
+
+// Get a copy of the committed operations, oldest first.
+//
+// The Time of each operation is the version of the anchor just after it was applied.
+func (h *History) Ops() []Op { return append([]Op(nil), h.ops...) }
+
+// Begin a new transaction. ErrTxnOpen will be returned if another transaction has not
+// been committed or rolled back.
+func (h *History) Begin() (*Txn, error) {
+	if h.txn != nil {
+		return nil, ErrTxnOpen
+	}
+	h.txn = &Txn{h: h}
+	return h.txn, nil
+}
+
+// Undo the last n committed operations, most recent first, and remove them from the
+// history. ErrUndo will be returned without undoing any operations if the history holds
+// fewer than n operations, and ErrTxnOpen will be returned if a transaction is open.
+func (h *History) Undo(n int) error {
+	if h.txn != nil {
+		return ErrTxnOpen
+	}
+	if n < 0 || n > len(h.ops) {
+		return ErrUndo
+	}
+	h.undo(h.ops[len(h.ops)-n:])
+	h.ops = h.ops[:len(h.ops)-n]
+	return nil
+}
+
+// Undo operations in reverse order.
+//
+//	UNDO(op)
+//	if op removed b then
+//	  ADDBUCKET()          ◃ b is on top of R
+//	else if op added b then
+//	  REMOVEBUCKET(b)      ◃ K[b] ← W[N], which is the successor b had before
+func (h *History) undo(ops []Op) {
+	for i := len(ops) - 1; i >= 0; i-- {
+		switch op := ops[i]; op.Kind {
+		case OpRemove:
+			h.a.addBucket(op.Bucket)
+		case OpAdd:
+			h.a.RemoveBucket(op.Bucket)
+		}
+	}
+}
+
+// Add a bucket to the anchor and return it.
+//
+// See Anchor.AddBucket for more information.
+func (t *Txn) AddBucket() uint32 {
+	t.check()
+	b := t.h.a.AddBucket()
+	t.ops = append(t.ops, Op{Kind: OpAdd, Bucket: b, Time: t.h.a.v})
+	return b
+}
+
+// Remove a bucket from the anchor.
+//
+// See Anchor.RemoveBucket for more information. Removals which have no effect are not
+// recorded.
+func (t *Txn) RemoveBucket(b uint32) {
+	t.check()
+	v := t.h.a.v
+	t.h.a.RemoveBucket(b)
+	if t.h.a.v != v {
+		t.ops = append(t.ops, Op{Kind: OpRemove, Bucket: b, Time: t.h.a.v})
+	}
+}
+
+// Commit the changes made in the transaction to the history.
+func (t *Txn) Commit() {
+	t.check()
+	t.h.ops = append(t.h.ops, t.ops...)
+	t.close()
+}
+
+// Undo the changes made in the transaction, returning the anchor to the state it was in
+// when the transaction began.
+func (t *Txn) Rollback() {
+	t.check()
+	t.h.undo(t.ops)
+	t.close()
+}
+
+func (t *Txn) close() {
+	t.closed = true
+	t.h.txn = nil
+}
+
+func (t *Txn) check() {
+	if t.closed {
+		panic("anchor: transaction already committed or rolled back")
+	}
+}
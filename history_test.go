@@ -0,0 +1,172 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// Check that two anchors are identical, apart from their versions.
+func sameState(a, b *Anchor) bool {
+	a, b = a.clone(), b.clone()
+	a.v, b.v = 0, 0
+	return reflect.DeepEqual(a, b)
+}
+
+func TestHistoryRollback(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	h := NewHistory(NewAnchor(32, 24))
+	for i := 0; i < 100; i++ {
+		before, version := h.Anchor(), h.Anchor().Version()
+		txn, err := h.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 1+rng.Intn(8); j++ {
+			if h.Anchor().Removed() > 0 && rng.Intn(2) == 0 {
+				txn.AddBucket()
+			} else {
+				txn.RemoveBucket(uint32(rng.Intn(32)))
+			}
+		}
+		if rng.Intn(2) == 0 {
+			txn.Commit()
+			continue
+		}
+		txn.Rollback()
+		if !sameState(h.Anchor(), before) {
+			t.Fatalf("rollback %v: anchor = %+v, expected %+v", i, h.Anchor(), before)
+		}
+		if h.Anchor().Version() < version {
+			t.Fatalf("rollback %v: version decreased from %v to %v", i, version, h.Anchor().Version())
+		}
+	}
+}
+
+func TestHistoryUndo(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	h := NewHistory(NewAnchor(32, 24))
+	states := []*Anchor{h.Anchor()}
+	for len(h.Ops()) < 50 {
+		txn, err := h.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Anchor().Removed() > 0 && rng.Intn(3) == 0 {
+			txn.AddBucket()
+		} else {
+			txn.RemoveBucket(h.Anchor().W[rng.Intn(h.Anchor().Len())])
+		}
+		txn.Commit()
+		states = append(states, h.Anchor())
+	}
+	for _, op := range h.Ops() {
+		if op.Kind != OpAdd && op.Kind != OpRemove {
+			t.Fatalf("invalid operation %+v", op)
+		}
+	}
+
+	for len(states) > 1 {
+		n := 1 + rng.Intn(min(5, len(states)-1))
+		if err := h.Undo(n); err != nil {
+			t.Fatal(err)
+		}
+		states = states[:len(states)-n]
+		if !sameState(h.Anchor(), states[len(states)-1]) || len(h.Ops()) != len(states)-1 {
+			t.Fatalf("after undo: anchor = %+v, expected %+v", h.Anchor(), states[len(states)-1])
+		}
+	}
+	if err := h.Undo(1); err != ErrUndo {
+		t.Fatalf("err = %v, expected %v", err, ErrUndo)
+	}
+}
+
+func TestHistoryUndoRemoval(t *testing.T) {
+	// A mistaken removal can be undone after later removals
+	h := NewHistory(NewAnchor(16, 16))
+	before := h.Anchor().Freeze()
+	txn, err := h.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn.RemoveBucket(3)
+	txn.Commit()
+	if txn, err = h.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	txn.RemoveBucket(8)
+	txn.RemoveBucket(8) // no effect
+	txn.RemoveBucket(12)
+	txn.Commit()
+	if len(h.Ops()) != 3 {
+		t.Fatalf("ops = %v, expected 3 operations", h.Ops())
+	}
+	if err := h.Undo(3); err != nil {
+		t.Fatal(err)
+	}
+	after := h.Anchor()
+	for k := uint64(0); k < 1e4; k++ {
+		if b, expected := after.GetBucket(k), before.GetBucket(k); b != expected {
+			t.Fatalf("key %v: bucket = %v, expected %v", k, b, expected)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("closed transaction did not panic")
+		}
+	}()
+	txn.RemoveBucket(1)
+}
+
+func TestHistoryOpenTxn(t *testing.T) {
+	h := NewHistory(NewAnchor(16, 16))
+	txn, err := h.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn.RemoveBucket(3)
+	if _, err := h.Begin(); err != ErrTxnOpen {
+		t.Fatalf("begin: err = %v, expected %v", err, ErrTxnOpen)
+	}
+	if err := h.Undo(0); err != ErrTxnOpen {
+		t.Fatalf("undo: err = %v, expected %v", err, ErrTxnOpen)
+	}
+	txn.Commit()
+	if txn, err = h.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	txn.Rollback()
+	if err := h.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes to the anchor returned by the history are not recorded or applied
+	a := h.Anchor()
+	a.RemoveBucket(5)
+	if !h.Anchor().IsWorking(5) {
+		t.Fatalf("bucket 5 removed from the history's anchor")
+	}
+}
@@ -0,0 +1,156 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"iter"
+	"unsafe"
+)
+
+// Get the number of removed buckets.
+func (a *Anchor) Removed() int { return len(a.R) }
+
+// Check if b is a working bucket.
+func (a *Anchor) IsWorking(b uint32) bool { return int(b) < len(a.A) && a.A[b] == 0 }
+
+// Iterate over the working buckets in their desired order (W[0..N-1]).
+//
+// The anchor must not be modified during iteration.
+func (a *Anchor) WorkingBuckets() iter.Seq[uint32] {
+	return func(yield func(uint32) bool) {
+		for _, b := range a.W[:a.N] {
+			if !yield(b) {
+				return
+			}
+		}
+	}
+}
+
+// Get a copy of the removed buckets in the order in which they were removed. The last
+// bucket will be the next bucket added by AddBucket.
+func (a *Anchor) RemovedBuckets() []uint32 { return append([]uint32(nil), a.R...) }
+
+// Get the approximate number of bytes of memory retained by the anchor.
+func (a *Anchor) MemoryBytes() int {
+	return int(unsafe.Sizeof(*a)) + 4*(cap(a.A)+cap(a.K)+cap(a.W)+cap(a.L)+cap(a.R))
+}
+
+// Get the number of removed buckets.
+func (a *CompactAnchor) Removed() int { return len(a.R) }
+
+// Check if b is a working bucket.
+func (a *CompactAnchor) IsWorking(b uint16) bool { return int(b) < len(a.A) && a.A[b] == 0 }
+
+// Iterate over the working buckets in their desired order (W[0..N-1]).
+//
+// The anchor must not be modified during iteration.
+func (a *CompactAnchor) WorkingBuckets() iter.Seq[uint16] {
+	return func(yield func(uint16) bool) {
+		for _, b := range a.W[:a.N] {
+			if !yield(b) {
+				return
+			}
+		}
+	}
+}
+
+// Get a copy of the removed buckets in the order in which they were removed. The last
+// bucket will be the next bucket added by AddBucket.
+func (a *CompactAnchor) RemovedBuckets() []uint16 { return append([]uint16(nil), a.R...) }
+
+// Get the approximate number of bytes of memory retained by the anchor.
+func (a *CompactAnchor) MemoryBytes() int {
+	return int(unsafe.Sizeof(*a)) + 2*(cap(a.A)+cap(a.K)+cap(a.W)+cap(a.L)+cap(a.R))
+}
+
+// Get the number of removed buckets.
+func (a *TinyAnchor) Removed() int { return len(a.R) }
+
+// Check if b is a working bucket.
+func (a *TinyAnchor) IsWorking(b uint8) bool { return int(b) < len(a.A) && a.A[b] == 0 }
+
+// Iterate over the working buckets in their desired order (W[0..N-1]).
+//
+// The anchor must not be modified during iteration.
+func (a *TinyAnchor) WorkingBuckets() iter.Seq[uint8] {
+	return func(yield func(uint8) bool) {
+		for _, b := range a.W[:a.N] {
+			if !yield(b) {
+				return
+			}
+		}
+	}
+}
+
+// Get a copy of the removed buckets in the order in which they were removed. The last
+// bucket will be the next bucket added by AddBucket.
+func (a *TinyAnchor) RemovedBuckets() []uint8 { return append([]uint8(nil), a.R...) }
+
+// Get the approximate number of bytes of memory retained by the anchor.
+func (a *TinyAnchor) MemoryBytes() int {
+	// All slices share a single allocation of 5 bytes per bucket
+	return int(unsafe.Sizeof(*a)) + 5*len(a.A)
+}
+
+// Get the number of removed buckets.
+func (t *TableAnchor) Removed() int { return t.a.Removed() }
+
+// Check if b is a working bucket.
+func (t *TableAnchor) IsWorking(b uint16) bool { return t.a.IsWorking(b) }
+
+// Iterate over the working buckets in their desired order (W[0..N-1]).
+//
+// The anchor must not be modified during iteration.
+func (t *TableAnchor) WorkingBuckets() iter.Seq[uint16] { return t.a.WorkingBuckets() }
+
+// Get a copy of the removed buckets in the order in which they were removed. The last
+// bucket will be the next bucket added by AddBucket.
+func (t *TableAnchor) RemovedBuckets() []uint16 { return t.a.RemovedBuckets() }
+
+// Get the approximate number of bytes of memory retained by the anchor, including the
+// lookup table.
+func (t *TableAnchor) MemoryBytes() int {
+	return t.a.MemoryBytes() + int(unsafe.Sizeof(*t)-unsafe.Sizeof(t.a)) + 2*cap(t.t) + 4*cap(t.off)
+}
+
+// Get the total number of buckets, including removed buckets.
+func (f *FrozenAnchor) Capacity() int { return len(f.a) }
+
+// Check if b is a working bucket.
+func (f *FrozenAnchor) IsWorking(b uint32) bool { return int(b) < len(f.a) && f.a[b] == 0 }
+
+// Get the approximate number of bytes of memory retained by the frozen anchor.
+func (f *FrozenAnchor) MemoryBytes() int {
+	return int(unsafe.Sizeof(*f)) + 4*(cap(f.a)+cap(f.k))
+}
+
+// Get the total number of buckets, including removed buckets.
+func (f *FrozenCompactAnchor) Capacity() int { return len(f.a) }
+
+// Check if b is a working bucket.
+func (f *FrozenCompactAnchor) IsWorking(b uint16) bool { return int(b) < len(f.a) && f.a[b] == 0 }
+
+// Get the approximate number of bytes of memory retained by the frozen anchor.
+func (f *FrozenCompactAnchor) MemoryBytes() int {
+	return int(unsafe.Sizeof(*f)) + 2*(cap(f.a)+cap(f.k))
+}
@@ -0,0 +1,85 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestIntrospection(t *testing.T) {
+	const (
+		buckets = 1000
+		used    = 900
+	)
+	a := NewAnchor(buckets, used)
+	a.RemoveBucket(5)
+	a.RemoveBucket(17)
+
+	if a.Capacity() != buckets || a.Len() != used-2 || a.Removed() != buckets-used+2 {
+		t.Fatalf("capacity = %v, len = %v, removed = %v", a.Capacity(), a.Len(), a.Removed())
+	}
+	if a.IsWorking(5) || a.IsWorking(950) || a.IsWorking(buckets) || !a.IsWorking(6) {
+		t.Fatal("unexpected working buckets")
+	}
+	working := slices.Collect(a.WorkingBuckets())
+	if len(working) != a.Len() || slices.Contains(working, 5) || slices.Contains(working, 17) {
+		t.Fatalf("working buckets = %v", working)
+	}
+	removed := a.RemovedBuckets()
+	if removed[0] != buckets-1 || removed[len(removed)-2] != 5 || removed[len(removed)-1] != 17 {
+		t.Fatalf("removed buckets = %v", removed)
+	}
+	removed[0] = 0
+	if a.R[0] != buckets-1 {
+		t.Fatal("RemovedBuckets returned internal state")
+	}
+
+	f := a.Freeze()
+	if f.Capacity() != buckets || f.IsWorking(5) || !f.IsWorking(6) {
+		t.Fatal("unexpected frozen working buckets")
+	}
+	if am, fm := a.MemoryBytes(), f.MemoryBytes(); am < 20*buckets || fm < 8*buckets || 2*fm > am+100 {
+		t.Fatalf("memory = %v, frozen memory = %v", am, fm)
+	}
+
+	c := NewCompactAnchor(buckets, used)
+	c.RemoveBucket(5)
+	c.RemoveBucket(17)
+	ti := NewTinyAnchor(200, 180)
+	ti.RemoveBucket(5)
+	ti.RemoveBucket(17)
+	if !reflect.DeepEqual(widen(slices.Collect(c.WorkingBuckets())), working) {
+		t.Fatal("compact working buckets differ")
+	}
+	if !reflect.DeepEqual(widen(c.RemovedBuckets()), a.RemovedBuckets()) {
+		t.Fatal("compact removed buckets differ")
+	}
+	if ti.Removed() != 22 || ti.IsWorking(17) || len(slices.Collect(ti.WorkingBuckets())) != 178 {
+		t.Fatal("unexpected tiny working buckets")
+	}
+	if m := ti.MemoryBytes(); m > 5*200+200 {
+		t.Fatalf("tiny memory = %v", m)
+	}
+}
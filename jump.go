@@ -0,0 +1,88 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+// Jump consistent hash implementation.
+//
+// Jump requires no memory beyond the number of working buckets, but buckets may only be
+// added or removed at the end of the range [0, n). RemoveBucket has no effect for any bucket
+// other than the last working bucket.
+//
+// [A Fast, Minimal Memory, Consistent Hash Algorithm]: https://arxiv.org/abs/1406.2294
+type Jump struct {
+	capacity, n uint32
+}
+
+// Create a new jump hash with a given capacity and initial size.
+//
+// Buckets 0 through used-1 will be working. At least one bucket must be working, and the
+// capacity may not exceed 2^32−1 buckets; NewJump panics otherwise.
+func NewJump(buckets, used int) *Jump {
+	if uint64(buckets) > 1<<32-1 || used < 1 || used > buckets {
+		panic("anchor: invalid capacity or size for a jump hash")
+	}
+	return &Jump{capacity: uint32(buckets), n: uint32(used)}
+}
+
+// Get the bucket which a hash-key is assigned to.
+//
+//	JUMPCONSISTENTHASH(k, n)
+//	b ← −1, j ← 0
+//	while j < n do
+//	  b ← j
+//	  k ← k × 2862933555777941757 + 1
+//	  j ← (b + 1) × 2³¹ / ((k >> 33) + 1)
+//	return b
+func (j *Jump) GetBucket(key uint64) uint32 {
+	key = mix64(key)
+	b, next := int64(-1), int64(0)
+	for next < int64(j.n) {
+		b = next
+		key = key*2862933555777941757 + 1
+		next = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return uint32(b)
+}
+
+// Add the bucket following the last working bucket. AddBucket panics if all buckets
+// are working.
+func (j *Jump) AddBucket() uint32 {
+	if j.n == j.capacity {
+		panic("anchor: no buckets to add")
+	}
+	j.n++
+	return j.n - 1
+}
+
+// Remove the last working bucket. Removing any other bucket has no effect.
+func (j *Jump) RemoveBucket(b uint32) {
+	if b == j.n-1 && j.n > 1 {
+		j.n--
+	}
+}
+
+// Get the number of working buckets.
+func (j *Jump) Len() int { return int(j.n) }
+
+// Get the total number of buckets, including removed buckets.
+func (j *Jump) Capacity() int { return int(j.capacity) }
@@ -0,0 +1,125 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import "slices"
+
+// MergeReport describes the cost of a merge to keys which were already assigned to
+// working buckets.
+type MergeReport struct {
+	// Reordered is the number of operations in the divergent logs which added a removed
+	// bucket other than the next bucket which AddBucket would have returned.
+	Reordered int
+	// Keys is the number of sampled keys.
+	Keys int
+	// Moved is the number of sampled keys which were moved by a reordered addition to a
+	// bucket other than the bucket which was added.
+	Moved int
+	// MovedFraction is Moved / Keys, or 0 if no keys were sampled.
+	MovedFraction float64
+}
+
+// Merge two divergent logs of changes to the working set of an anchor.
+//
+// All agents begin with NewAnchor(buckets, used) and apply the common ancestor log. The
+// left and right logs hold the operations applied by each side after they diverged from
+// the ancestor. The operations in both logs are merged into a single order by Time, then
+// by Node, Kind and Bucket; operations which appear in both logs are applied once. Every
+// agent which merges the same logs, in either order, will compute identical results.
+//
+// Operations in the divergent logs which have no effect on the merged anchor are dropped:
+// a bucket removed by both sides is removed once, and a bucket which is already working
+// is not added again. Removing the last working bucket also has no effect.
+//
+// A bucket which was removed on one side may be added on the other side even when it is
+// not the next bucket which AddBucket would return. The buckets removed after it are
+// added back, the bucket is added, and the others are removed again in their original
+// order, leaving the anchor as if the bucket had never been removed. Unlike AddBucket,
+// such a reordered addition does not only move keys to the added bucket: keys may also
+// move between buckets which were working before and after it. With 50 buckets and 20
+// removed at random, a single reordered addition typically moves over a tenth of all keys
+// between other buckets. The report counts the reordered additions and estimates the fraction of keys
+// which they moved from the given sample of keys.
+//
+// Merge returns the merged log, which begins with the ancestor log and includes only
+// operations which changed the working set, together with the resulting anchor. The
+// version of the anchor is the length of the merged log. ErrInvalidOp will be returned
+// if any operation has an invalid kind or an out-of-range bucket, or if any operation
+// in the ancestor log has no effect.
+func Merge(buckets, used int, ancestor, left, right []Op, sampleKeys []uint64) ([]Op, *Anchor, *MergeReport, error) {
+	if buckets <= 0 || uint64(buckets) > 1<<32-1 || used <= 0 || used > buckets {
+		return nil, nil, nil, ErrInvalidWorkingSet
+	}
+	a := NewAnchor(buckets, used)
+	log := make([]Op, 0, len(ancestor)+len(left)+len(right))
+	for _, op := range ancestor {
+		if !op.valid(buckets) || !a.apply(op) {
+			return nil, nil, nil, ErrInvalidOp
+		}
+		log = append(log, op)
+	}
+
+	divergent := make([]Op, 0, len(left)+len(right))
+	divergent = append(append(divergent, left...), right...)
+	for _, op := range divergent {
+		if !op.valid(buckets) {
+			return nil, nil, nil, ErrInvalidOp
+		}
+	}
+	slices.SortFunc(divergent, Op.compare)
+	r := &MergeReport{Keys: len(sampleKeys)}
+	before, moved := make([]uint32, len(sampleKeys)), make([]bool, len(sampleKeys))
+	for _, op := range slices.Compact(divergent) {
+		reordered := op.Kind == OpAdd && !a.IsWorking(op.Bucket) && a.R[len(a.R)-1] != op.Bucket
+		if reordered {
+			a.GetBuckets(sampleKeys, before)
+		}
+		if !a.apply(op) {
+			continue
+		}
+		log = append(log, op)
+		if reordered {
+			r.Reordered++
+			for i, key := range sampleKeys {
+				if b := a.GetBucket(key); b != before[i] && b != op.Bucket {
+					moved[i] = true
+				}
+			}
+		}
+	}
+	for _, m := range moved {
+		if m {
+			r.Moved++
+		}
+	}
+	if r.Keys > 0 {
+		r.MovedFraction = float64(r.Moved) / float64(r.Keys)
+	}
+	a.v = uint64(len(log))
+	return log, a, r, nil
+}
+
+// Check if an operation may be applied to an anchor with the given capacity.
+func (op Op) valid(buckets int) bool {
+	return (op.Kind == OpAdd || op.Kind == OpRemove) && uint64(op.Bucket) < uint64(buckets)
+}
@@ -0,0 +1,174 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestAddSpecificBucket(t *testing.T) {
+	a := NewAnchor(10, 10)
+	a.RemoveBucket(1)
+	a.RemoveBucket(2)
+	a.RemoveBucket(3)
+	a.addBucket(1)
+
+	// Adding 1 must leave the anchor as if 1 had never been removed
+	expected := NewAnchor(10, 10)
+	expected.RemoveBucket(2)
+	expected.RemoveBucket(3)
+	expected.v = 4
+	if !reflect.DeepEqual(a, expected) {
+		t.Fatalf("anchor = %+v, expected %+v", a, expected)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	ancestor := []Op{
+		{Kind: OpRemove, Bucket: 5, Time: 1, Node: 1},
+		{Kind: OpRemove, Bucket: 9, Time: 2, Node: 2},
+	}
+	left := []Op{
+		{Kind: OpRemove, Bucket: 2, Time: 3, Node: 1},
+		{Kind: OpRemove, Bucket: 7, Time: 5, Node: 1},
+	}
+	right := []Op{
+		{Kind: OpRemove, Bucket: 7, Time: 3, Node: 2},
+		{Kind: OpAdd, Bucket: 9, Time: 4, Node: 2},
+		{Kind: OpRemove, Bucket: 2, Time: 3, Node: 1}, // also seen by the right side
+	}
+	log, a, r, err := Merge(10, 10, ancestor, left, right, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedLog := []Op{
+		ancestor[0],
+		ancestor[1],
+		{Kind: OpRemove, Bucket: 2, Time: 3, Node: 1},
+		{Kind: OpRemove, Bucket: 7, Time: 3, Node: 2},
+		{Kind: OpAdd, Bucket: 9, Time: 4, Node: 2},
+	}
+	if !reflect.DeepEqual(log, expectedLog) {
+		t.Fatalf("merged log = %v, expected %v", log, expectedLog)
+	}
+	if a.Version() != uint64(len(log)) || !a.valid() {
+		t.Fatalf("version = %v, valid = %v", a.Version(), a.valid())
+	}
+	if removed := a.RemovedBuckets(); !reflect.DeepEqual(removed, []uint32{5, 2, 7}) {
+		t.Fatalf("removed buckets = %v, expected [5 2 7]", removed)
+	}
+	if r.Reordered != 1 {
+		t.Fatalf("reordered additions = %v, expected 1", r.Reordered)
+	}
+
+	// Every agent must compute the same result, whichever side it was on
+	swappedLog, b, _, err := Merge(10, 10, ancestor, right, left, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(swappedLog, log) || !reflect.DeepEqual(a, b) {
+		t.Fatalf("merge depends on the order of the divergent logs")
+	}
+
+	// Replaying the merged log as the ancestor must produce the same anchor
+	if _, replayed, _, err := Merge(10, 10, log, nil, nil, nil); err != nil || !reflect.DeepEqual(replayed, a) {
+		t.Fatalf("replayed merged log differs (err = %v)", err)
+	}
+}
+
+func TestMergeReordered(t *testing.T) {
+	const (
+		buckets = 50
+		removed = 20
+		trials  = 200
+	)
+	rng := rand.New(rand.NewSource(1))
+	keys := make([]uint64, 2e4)
+	for i := range keys {
+		keys[i] = rng.Uint64()
+	}
+	moved := 0
+	for trial := 0; trial < trials; trial++ {
+		var ancestor []Op
+		for i, b := range rng.Perm(buckets)[:removed] {
+			ancestor = append(ancestor, Op{Kind: OpRemove, Bucket: uint32(b), Time: uint64(i)})
+		}
+		// Add the next bucket on one side, or any other removed bucket
+		i := removed - 1
+		if trial%2 == 1 {
+			i = rng.Intn(removed - 1)
+		}
+		left := []Op{{Kind: OpAdd, Bucket: ancestor[i].Bucket, Time: removed}}
+		_, _, r, err := Merge(buckets, buckets, ancestor, left, nil, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trial%2 == 0 {
+			if r.Reordered != 0 || r.Moved != 0 {
+				t.Fatalf("trial %v: next bucket added, report = %+v", trial, *r)
+			}
+			continue
+		}
+		if r.Reordered != 1 || r.Keys != len(keys) {
+			t.Fatalf("trial %v: report = %+v", trial, *r)
+		}
+		moved += r.Moved
+	}
+	fraction := float64(moved) / float64(len(keys)*trials/2)
+	t.Logf("reordered additions moved %.1f%% of keys between other buckets", 100*fraction)
+	if fraction < 0.1 {
+		t.Fatalf("reordered additions moved %v of keys, expected over a tenth", fraction)
+	}
+}
+
+func TestMergeLastBucket(t *testing.T) {
+	left := []Op{{Kind: OpRemove, Bucket: 0, Time: 1, Node: 1}}
+	right := []Op{{Kind: OpRemove, Bucket: 1, Time: 1, Node: 2}}
+	log, a, _, err := Merge(2, 2, nil, left, right, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 1 || a.Len() != 1 || !a.IsWorking(1) {
+		t.Fatalf("merged log = %v, working = %v", log, a.W[:a.N])
+	}
+}
+
+func TestMergeInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		ancestor, left []Op
+	}{
+		{left: []Op{{Kind: OpRemove, Bucket: 10}}},
+		{left: []Op{{Kind: 0, Bucket: 1}}},
+		{ancestor: []Op{{Kind: OpAdd, Bucket: 1}}},
+		{ancestor: []Op{{Kind: OpRemove, Bucket: 1}, {Kind: OpRemove, Bucket: 1}}},
+	} {
+		if _, _, _, err := Merge(10, 10, tc.ancestor, tc.left, nil, nil); err != ErrInvalidOp {
+			t.Fatalf("Merge(%v, %v): err = %v, expected %v", tc.ancestor, tc.left, err, ErrInvalidOp)
+		}
+	}
+	if _, _, _, err := Merge(10, 0, nil, nil, nil, nil); err != ErrInvalidWorkingSet {
+		t.Fatalf("err = %v, expected %v", err, ErrInvalidWorkingSet)
+	}
+}
@@ -0,0 +1,197 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidOp is returned when an operation cannot be applied to an anchor.
+var ErrInvalidOp = errors.New("anchor: invalid operation")
+
+// OpKind identifies a change to the working set of an anchor.
+type OpKind uint8
+
+const (
+	// OpAdd adds a removed bucket to the working set.
+	OpAdd OpKind = iota + 1
+	// OpRemove removes a working bucket from the working set.
+	OpRemove
+)
+
+// Get the name of the kind of operation.
+func (k OpKind) String() string {
+	switch k {
+	case OpAdd:
+		return "add"
+	case OpRemove:
+		return "remove"
+	}
+	return "invalid"
+}
+
+// Op is a single change to the working set of an anchor, as recorded in a log of changes.
+type Op struct {
+	// Kind is either OpAdd or OpRemove.
+	Kind OpKind
+	// Bucket is the bucket which was added or removed.
+	Bucket uint32
+	// Time is a logical (e.g. Lamport) timestamp for the operation.
+	Time uint64
+	// Node identifies the agent which issued the operation.
+	Node uint32
+}
+
+// Compare the operations by time, then by node, kind and bucket.
+func (op Op) compare(other Op) int {
+	return cmp.Or(
+		cmp.Compare(op.Time, other.Time),
+		cmp.Compare(op.Node, other.Node),
+		cmp.Compare(op.Kind, other.Kind),
+		cmp.Compare(op.Bucket, other.Bucket),
+	)
+}
+
+// Apply an operation to the anchor, returning false if the operation had no effect.
+//
+// Removing a bucket which is not working, or the last working bucket, has no effect, as
+// does adding a bucket which is already working. A removed bucket may be added even if
+// it is not the next bucket which AddBucket would return, at the cost of moving keys
+// between other buckets; see addBucket.
+func (a *Anchor) apply(op Op) bool {
+	switch op.Kind {
+	case OpAdd:
+		if a.IsWorking(op.Bucket) || int(op.Bucket) >= len(a.A) {
+			return false
+		}
+		a.addBucket(op.Bucket)
+		return true
+	case OpRemove:
+		if !a.IsWorking(op.Bucket) || a.N == 1 {
+			return false
+		}
+		a.RemoveBucket(op.Bucket)
+		return true
+	}
+	return false
+}
+
+// Add a specific removed bucket to the anchor.
+//
+// Buckets are always added in the reverse of the order in which they were removed, so
+// the buckets removed after b are added first. Once b has been added, the other buckets
+// are removed again in their original order, leaving R as it was without b. The anchor
+// is left as if b had never been removed, so unless b was on top of R, keys may also
+// move between buckets which were working before and after b was added.
+//
+//	ADDBUCKET(b)
+//	i ← index of b in R
+//	S ← R[i+1 ..]
+//	for j = 1 to |S| do
+//	  ADDBUCKET()
+//	ADDBUCKET()              ◃ b is on top of R
+//	for c in S do
+//	  REMOVEBUCKET(c)
+//
+// The version is incremented once.
+func (a *Anchor) addBucket(b uint32) {
+	i := len(a.R) - 1
+	for a.R[i] != b {
+		i--
+	}
+	v, later := a.v, append([]uint32(nil), a.R[i+1:]...)
+	for range later {
+		a.AddBucket()
+	}
+	a.AddBucket()
+	for _, c := range later {
+		a.RemoveBucket(c)
+	}
+	a.v = v + 1
+}
+
+// Apply a batch of operations to the anchor, or none of them.
+//
+// The whole batch is validated before any operation is applied: each bucket removed must
+// be working at that point in the batch (so no bucket may be removed twice without being
+// added in between), each bucket added must be the next bucket which AddBucket would
+// return at that point in the batch, and the last working bucket may not be removed. If
+// any operation is invalid, an error wrapping ErrInvalidOp will be returned and the anchor
+// will not be modified. The Time and Node of each operation are ignored.
+//
+// Since buckets are added in the reverse of the order in which they were removed, each
+// addition only moves keys to the added bucket. The version is incremented once for each
+// operation.
+func (a *Anchor) ApplyBatch(ops []Op) error {
+	if err := a.validateBatch(ops); err != nil {
+		return err
+	}
+	for _, op := range ops {
+		a.apply(op)
+	}
+	return nil
+}
+
+// Check that each operation in a batch will change the working set when applied in order,
+// and that each addition pops the bucket on top of R.
+func (a *Anchor) validateBatch(ops []Op) error {
+	working, n := make(map[uint32]bool), a.N
+	// R is tracked through the batch as the unchanged a.R[:top], followed by the buckets
+	// removed within the batch which have not been added again.
+	top, removed := len(a.R), []uint32(nil)
+	for i, op := range ops {
+		if !op.valid(len(a.A)) {
+			return fmt.Errorf("%w: operation %d has kind %v and bucket %d", ErrInvalidOp, i, op.Kind, op.Bucket)
+		}
+		isWorking, changed := working[op.Bucket]
+		if !changed {
+			isWorking = a.A[op.Bucket] == 0
+		}
+		switch {
+		case op.Kind == OpAdd && isWorking:
+			return fmt.Errorf("%w: operation %d adds working bucket %d", ErrInvalidOp, i, op.Bucket)
+		case op.Kind == OpRemove && !isWorking:
+			return fmt.Errorf("%w: operation %d removes non-working bucket %d", ErrInvalidOp, i, op.Bucket)
+		case op.Kind == OpRemove && n == 1:
+			return fmt.Errorf("%w: operation %d removes the last working bucket %d", ErrInvalidOp, i, op.Bucket)
+		}
+		if working[op.Bucket] = op.Kind == OpAdd; op.Kind == OpRemove {
+			removed = append(removed, op.Bucket)
+			n--
+			continue
+		}
+		var next uint32
+		if len(removed) > 0 {
+			next, removed = removed[len(removed)-1], removed[:len(removed)-1]
+		} else {
+			next, top = a.R[top-1], top-1
+		}
+		if op.Bucket != next {
+			return fmt.Errorf("%w: operation %d adds bucket %d before bucket %d", ErrInvalidOp, i, op.Bucket, next)
+		}
+		n++
+	}
+	return nil
+}
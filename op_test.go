@@ -0,0 +1,134 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestApplyBatch(t *testing.T) {
+	a, expected := NewAnchor(20, 16), NewAnchor(20, 16)
+	ops := []Op{
+		{Kind: OpRemove, Bucket: 3},
+		{Kind: OpRemove, Bucket: 7},
+		{Kind: OpAdd, Bucket: 7},
+		{Kind: OpAdd, Bucket: 3},
+		{Kind: OpAdd, Bucket: 16},
+		{Kind: OpRemove, Bucket: 3},
+	}
+	if err := a.ApplyBatch(ops); err != nil {
+		t.Fatal(err)
+	}
+	expected.RemoveBucket(3)
+	expected.RemoveBucket(7)
+	expected.AddBucket()
+	expected.AddBucket()
+	expected.AddBucket()
+	expected.RemoveBucket(3)
+	if !reflect.DeepEqual(a, expected) {
+		t.Fatalf("anchor = %+v, expected %+v", a, expected)
+	}
+
+	for name, batch := range map[string][]Op{
+		"duplicate removal":    {{Kind: OpRemove, Bucket: 1}, {Kind: OpRemove, Bucket: 2}, {Kind: OpRemove, Bucket: 1}},
+		"working bucket added": {{Kind: OpRemove, Bucket: 1}, {Kind: OpAdd, Bucket: 2}},
+		"too many additions":   {{Kind: OpAdd, Bucket: 3}, {Kind: OpAdd, Bucket: 17}, {Kind: OpAdd, Bucket: 18}, {Kind: OpAdd, Bucket: 19}, {Kind: OpAdd, Bucket: 19}},
+		"reordered addition":   {{Kind: OpRemove, Bucket: 1}, {Kind: OpRemove, Bucket: 2}, {Kind: OpAdd, Bucket: 1}},
+		"addition below top":   {{Kind: OpAdd, Bucket: 18}},
+		"out of range":         {{Kind: OpRemove, Bucket: 1}, {Kind: OpRemove, Bucket: 20}},
+		"invalid kind":         {{Kind: OpRemove, Bucket: 1}, {Bucket: 2}},
+	} {
+		before := a.clone()
+		if err := a.ApplyBatch(batch); !errors.Is(err, ErrInvalidOp) {
+			t.Fatalf("%v: err = %v, expected %v", name, err, ErrInvalidOp)
+		}
+		if !reflect.DeepEqual(a, before) {
+			t.Fatalf("%v: anchor modified by an invalid batch", name)
+		}
+	}
+
+	last := NewAnchor(3, 2)
+	if err := last.ApplyBatch([]Op{{Kind: OpRemove, Bucket: 0}, {Kind: OpRemove, Bucket: 1}}); !errors.Is(err, ErrInvalidOp) {
+		t.Fatalf("err = %v, expected %v", err, ErrInvalidOp)
+	}
+	if last.Len() != 2 {
+		t.Fatalf("anchor modified by an invalid batch")
+	}
+}
+
+func TestConcurrentAnchor(t *testing.T) {
+	const batchSize = 10
+	c := NewConcurrentAnchor(1000, 1000)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := uint64(0); ; k++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				f := c.Snapshot()
+				if f.Version()%batchSize != 0 {
+					t.Errorf("observed intermediate version %v", f.Version())
+					return
+				}
+				if b := f.GetBucket(k); !f.IsWorking(b) {
+					t.Errorf("key %v assigned to removed bucket %v", k, b)
+					return
+				}
+				c.GetBucket(k)
+			}
+		}()
+	}
+
+	for i := uint32(0); i < 50; i++ {
+		ops := make([]Op, batchSize)
+		for j := range ops {
+			ops[j] = Op{Kind: OpRemove, Bucket: i*batchSize + uint32(j)}
+		}
+		if err := c.ApplyBatch(ops); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.ApplyBatch([]Op{{Kind: OpRemove, Bucket: 999}, {Kind: OpRemove, Bucket: 0}}); !errors.Is(err, ErrInvalidOp) {
+		t.Fatalf("err = %v, expected %v", err, ErrInvalidOp)
+	}
+	close(done)
+	wg.Wait()
+
+	if c.Version() != 500 {
+		t.Fatalf("version = %v, expected 500", c.Version())
+	}
+	for b := uint32(0); b < 500; b++ {
+		if c.Snapshot().IsWorking(b) {
+			t.Fatalf("bucket %v was not removed", b)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// An intentionally simple reference implementation of AnchorHash, following Algorithm 1 in
+// the paper: the working set Wb just after the removal of each removed bucket b is stored
+// explicitly, rather than being reconstructed through the successors in K.
+//
+// Each working set is ordered as W[0..N-1] is ordered in Anchor (the last working bucket
+// replaces the removed bucket), so that hash(k) mod |Wb| selects the same bucket.
+type refAnchor struct {
+	capacity int
+	// w is the current working set.
+	w []uint32
+	// wb holds Wb for each removed bucket b.
+	wb map[uint32][]uint32
+	// in holds the members of Wb for each removed bucket b.
+	in map[uint32]map[uint32]bool
+	// succ holds the bucket which replaced each removed bucket b in the working set.
+	succ map[uint32]uint32
+	// prev holds the working set before each removal, in removal order.
+	prev [][]uint32
+	// r holds removed buckets in removal order.
+	r []uint32
+}
+
+func newRefAnchor(buckets, used int) *refAnchor {
+	ref := &refAnchor{
+		capacity: buckets,
+		wb:       make(map[uint32][]uint32),
+		in:       make(map[uint32]map[uint32]bool),
+		succ:     make(map[uint32]uint32),
+	}
+	for b := 0; b < buckets; b++ {
+		ref.w = append(ref.w, uint32(b))
+	}
+	for b := buckets - 1; b >= used; b-- {
+		ref.remove(uint32(b))
+	}
+	return ref
+}
+
+// GETBUCKET(k)
+// b ← hash(k) mod a
+// while b ∉ W do
+//
+//	b ← Wb[hb(k) mod |Wb|]
+//
+// return b
+func (ref *refAnchor) getBucket(key uint64) uint32 {
+	ha, hb, hc, hd := fleaInit(key)
+	b := fastMod(uint64(hd), uint64(ref.capacity))
+	for {
+		wb, removed := ref.wb[b]
+		if !removed {
+			return b
+		}
+		ha, hb, hc, hd = fleaRound(ha, hb, hc, hd)
+		b = wb[fastMod(uint64(hd), uint64(len(wb)))]
+	}
+}
+
+// Get the buckets visited while searching for the bucket which a key is assigned to, in
+// the same form as Anchor.GetPath: after each rehash, the search for Wb[h] visits h and
+// then each bucket which replaced the previous one in the working set, until it reaches
+// a member of Wb. The final bucket must equal getBucket(key).
+func (ref *refAnchor) getPath(key uint64) []uint32 {
+	ha, hb, hc, hd := fleaInit(key)
+	b := fastMod(uint64(hd), uint64(ref.capacity))
+	path := []uint32{b}
+	for {
+		if _, removed := ref.wb[b]; !removed {
+			return path
+		}
+		ha, hb, hc, hd = fleaRound(ha, hb, hc, hd)
+		h := fastMod(uint64(hd), uint64(len(ref.wb[b])))
+		path = append(path, h)
+		for !ref.in[b][h] {
+			h = ref.succ[h]
+			path = append(path, h)
+		}
+		b = h
+	}
+}
+
+func (ref *refAnchor) add() uint32 {
+	b := ref.r[len(ref.r)-1]
+	ref.r = ref.r[:len(ref.r)-1]
+	ref.w = ref.prev[len(ref.prev)-1]
+	ref.prev = ref.prev[:len(ref.prev)-1]
+	delete(ref.wb, b)
+	delete(ref.in, b)
+	delete(ref.succ, b)
+	return b
+}
+
+func (ref *refAnchor) remove(b uint32) {
+	i := -1
+	for j, w := range ref.w {
+		if w == b {
+			i = j
+		}
+	}
+	if i < 0 || len(ref.w) == 1 {
+		return
+	}
+	next := append([]uint32(nil), ref.w...)
+	next[i] = next[len(next)-1]
+	ref.succ[b] = next[i]
+	next = next[:len(next)-1]
+	ref.in[b] = make(map[uint32]bool, len(next))
+	for _, w := range next {
+		ref.in[b][w] = true
+	}
+	ref.prev, ref.r = append(ref.prev, ref.w), append(ref.r, b)
+	ref.w, ref.wb[b] = next, next
+}
+
+func TestReference(t *testing.T) {
+	const (
+		buckets = 200
+		used    = 150
+	)
+	rng := rand.New(rand.NewSource(1))
+	ref := newRefAnchor(buckets, used)
+	a := NewAnchor(buckets, used)
+	c := NewCompactAnchor(buckets, used)
+	ta := NewTableAnchor(buckets, used)
+	ti := NewTinyAnchor(buckets, used)
+	h := New(buckets, used)
+	hc := NewCompact(buckets, used)
+
+	keys := make([]uint64, 2000)
+	for i := range keys {
+		keys[i] = rng.Uint64()
+	}
+	batch, compactBatch := make([]uint32, len(keys)), make([]uint16, len(keys))
+	for step := 0; step < 300; step++ {
+		if len(ref.r) > 0 && rng.Intn(3) == 0 {
+			rb := ref.add()
+			added := []uint32{
+				a.AddBucket(), uint32(c.AddBucket()), uint32(ta.AddBucket()),
+				uint32(ti.AddBucket()), h.AddBucket(), uint32(hc.AddBucket()),
+			}
+			for j, b := range added {
+				if b != rb {
+					t.Fatalf("step %v: variant %v added bucket %v, reference added %v", step, j, b, rb)
+				}
+			}
+		} else {
+			b := uint32(rng.Intn(buckets))
+			ref.remove(b)
+			a.RemoveBucket(b)
+			c.RemoveBucket(uint16(b))
+			ta.RemoveBucket(uint16(b))
+			ti.RemoveBucket(uint8(b))
+			h.RemoveBucket(b)
+			hc.RemoveBucket(uint16(b))
+		}
+
+		f, fc := a.Freeze(), c.Freeze()
+		a.GetBuckets(keys, batch)
+		c.GetBuckets(keys, compactBatch)
+		for i, key := range keys {
+			rb, rpath := ref.getBucket(key), ref.getPath(key)
+			if rpath[len(rpath)-1] != rb {
+				t.Fatalf("step %v: key %v: reference path = %v, reference bucket = %v", step, key, rpath, rb)
+			}
+			buckets := []uint32{
+				a.GetBucket(key), batch[i], f.GetBucket(key), h.GetBucket(key),
+				uint32(c.GetBucket(key)), uint32(compactBatch[i]), uint32(fc.GetBucket(key)),
+				uint32(ta.GetBucket(key)), uint32(ti.GetBucket(key)), uint32(hc.GetBucket(key)),
+			}
+			for j, b := range buckets {
+				if b != rb {
+					t.Fatalf("step %v: key %v: variant %v bucket = %v, reference bucket = %v", step, key, j, b, rb)
+				}
+			}
+			paths := [][]uint32{
+				a.GetPath(key, nil), f.GetPath(key, nil), h.GetPath(key, nil), a.Explain(key).Path(),
+				widen(c.GetPath(key, nil)), widen(fc.GetPath(key, nil)), widen(hc.GetPath(key, nil)),
+				widen8(ti.GetPath(key, nil)),
+			}
+			for j, p := range paths {
+				if !reflect.DeepEqual(p, rpath) {
+					t.Fatalf("step %v: key %v: variant %v path = %v, reference path = %v", step, key, j, p, rpath)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+// Rendezvous (highest random weight) hash implementation.
+//
+// Each key is assigned to the working bucket with the highest score for the key, so
+// lookups require time proportional to the capacity.
+//
+// [A Name-Based Mapping Scheme for Rendezvous]: https://www.eecs.umich.edu/techreports/cse/96/CSE-TR-316-96.pdf
+type Rendezvous struct {
+	// working[b] is true if b is a working bucket.
+	working []bool
+	// r saves removed buckets in a LIFO order for future bucket additions.
+	r []uint32
+	n uint32
+}
+
+// Create a new rendezvous hash with a given capacity and initial size.
+//
+// Buckets 0 through used-1 will be working. At least one bucket must be working, and the
+// capacity may not exceed 2^32−1 buckets; NewRendezvous panics otherwise.
+func NewRendezvous(buckets, used int) *Rendezvous {
+	if uint64(buckets) > 1<<32-1 || used < 1 || used > buckets {
+		panic("anchor: invalid capacity or size for a rendezvous hash")
+	}
+	h := &Rendezvous{
+		working: make([]bool, buckets),
+		r:       make([]uint32, 0, buckets),
+		n:       uint32(used),
+	}
+	for b := 0; b < used; b++ {
+		h.working[b] = true
+	}
+	for b := buckets - 1; b >= used; b-- {
+		h.r = append(h.r, uint32(b))
+	}
+	return h
+}
+
+// Get the bucket which a hash-key is assigned to.
+//
+//	GETBUCKET(k)
+//	return argmax(b ∈ W) score(k, b)
+func (h *Rendezvous) GetBucket(key uint64) uint32 {
+	key = mix64(key)
+	best, bestScore := uint32(0), uint64(0)
+	for b, working := range h.working {
+		if !working {
+			continue
+		}
+		if score := mix64(key ^ mix64(uint64(b))); score >= bestScore {
+			best, bestScore = uint32(b), score
+		}
+	}
+	return best
+}
+
+// Add the most recently removed bucket. AddBucket panics if all buckets are working.
+func (h *Rendezvous) AddBucket() uint32 {
+	if len(h.r) == 0 {
+		panic("anchor: no buckets to add")
+	}
+	b := h.r[len(h.r)-1]
+	h.r = h.r[:len(h.r)-1]
+	h.working[b] = true
+	h.n++
+	return b
+}
+
+// Remove a working bucket. The last working bucket cannot be removed.
+func (h *Rendezvous) RemoveBucket(b uint32) {
+	if b >= uint32(len(h.working)) || !h.working[b] || h.n == 1 {
+		return
+	}
+	h.working[b] = false
+	h.r = append(h.r, b)
+	h.n--
+}
+
+// Get the number of working buckets.
+func (h *Rendezvous) Len() int { return int(h.n) }
+
+// Get the total number of buckets, including removed buckets.
+func (h *Rendezvous) Capacity() int { return len(h.working) }
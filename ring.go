@@ -0,0 +1,135 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import "sort"
+
+// Ring (Karger) consistent hash implementation.
+//
+// Each working bucket is placed at a number of pseudo-random points (replicas) on a
+// ring, and each key is assigned to the bucket at the first point following the key's
+// position on the ring. Balance improves with the number of replicas per bucket, at the
+// cost of memory and lookup time.
+type Ring struct {
+	// points holds the replicas of all working buckets in ascending order of position.
+	points   []ringPoint
+	replicas int
+	working  []bool
+	// r saves removed buckets in a LIFO order for future bucket additions.
+	r []uint32
+	n uint32
+}
+
+type ringPoint struct {
+	pos    uint64
+	bucket uint32
+}
+
+// Create a new ring hash with a given capacity, initial size and replicas per bucket.
+//
+// Buckets 0 through used-1 will be working. At least one bucket must be working, each
+// bucket must have at least one replica, and the capacity may not exceed 2^32−1 buckets;
+// NewRing panics otherwise.
+func NewRing(buckets, used, replicas int) *Ring {
+	if uint64(buckets) > 1<<32-1 || used < 1 || used > buckets || replicas < 1 {
+		panic("anchor: invalid capacity, size or replicas for a ring hash")
+	}
+	h := &Ring{
+		points:   make([]ringPoint, 0, used*replicas),
+		replicas: replicas,
+		working:  make([]bool, buckets),
+		r:        make([]uint32, 0, buckets),
+		n:        uint32(used),
+	}
+	for b := 0; b < used; b++ {
+		h.working[b] = true
+		h.points = h.appendReplicas(h.points, uint32(b))
+	}
+	for b := buckets - 1; b >= used; b-- {
+		h.r = append(h.r, uint32(b))
+	}
+	h.sortPoints()
+	return h
+}
+
+// Get the bucket which a hash-key is assigned to.
+func (h *Ring) GetBucket(key uint64) uint32 {
+	pos := mix64(key)
+	i := sort.Search(len(h.points), func(i int) bool { return h.points[i].pos >= pos })
+	if i == len(h.points) {
+		i = 0
+	}
+	return h.points[i].bucket
+}
+
+// Add the most recently removed bucket. AddBucket panics if all buckets are working.
+func (h *Ring) AddBucket() uint32 {
+	if len(h.r) == 0 {
+		panic("anchor: no buckets to add")
+	}
+	b := h.r[len(h.r)-1]
+	h.r = h.r[:len(h.r)-1]
+	h.working[b] = true
+	h.n++
+	h.points = h.appendReplicas(h.points, b)
+	h.sortPoints()
+	return b
+}
+
+// Remove a working bucket. The last working bucket cannot be removed.
+func (h *Ring) RemoveBucket(b uint32) {
+	if b >= uint32(len(h.working)) || !h.working[b] || h.n == 1 {
+		return
+	}
+	h.working[b] = false
+	h.r = append(h.r, b)
+	h.n--
+	points := h.points[:0]
+	for _, p := range h.points {
+		if p.bucket != b {
+			points = append(points, p)
+		}
+	}
+	h.points = points
+}
+
+// Get the number of working buckets.
+func (h *Ring) Len() int { return int(h.n) }
+
+// Get the total number of buckets, including removed buckets.
+func (h *Ring) Capacity() int { return len(h.working) }
+
+func (h *Ring) appendReplicas(points []ringPoint, b uint32) []ringPoint {
+	seed := mix64(uint64(b) + 1)
+	for i := 0; i < h.replicas; i++ {
+		points = append(points, ringPoint{mix64(seed + uint64(i)), b})
+	}
+	return points
+}
+
+func (h *Ring) sortPoints() {
+	sort.Slice(h.points, func(i, j int) bool {
+		pi, pj := h.points[i], h.points[j]
+		return pi.pos < pj.pos || (pi.pos == pj.pos && pi.bucket < pj.bucket)
+	})
+}
@@ -0,0 +1,231 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"math"
+	"sort"
+)
+
+// Stats describes the distribution of a sample of keys across the working buckets of an
+// anchor, and the lengths of the paths followed to assign them.
+type Stats struct {
+	// Keys is the number of sampled keys.
+	Keys int
+	// Working is the number of working buckets.
+	Working int
+	// Load holds the number of sampled keys assigned to each bucket. Removed buckets will
+	// always have a load of 0.
+	Load []int
+	// MinLoad and MaxLoad are the smallest and largest loads of any working bucket.
+	MinLoad, MaxLoad int
+	// MeanLoad is the expected load of each working bucket, Keys / Working.
+	MeanLoad float64
+	// StdDev is the standard deviation of the loads of the working buckets.
+	StdDev float64
+	// MaxImbalance and MinImbalance are MaxLoad / MeanLoad and MinLoad / MeanLoad.
+	MaxImbalance, MinImbalance float64
+	// ChiSquare is the chi-square statistic for the loads of the working buckets, compared
+	// against a uniform distribution.
+	ChiSquare float64
+	// PValue is the probability of a chi-square statistic at least as large as ChiSquare
+	// for a uniform distribution. Very small values indicate an unbalanced distribution.
+	PValue float64
+	// MeanPath is the mean number of hashes computed to assign each key: one for the
+	// initial bucket, and one for each removed bucket from which the search rehashed.
+	// Successors followed through K are not counted; see MeanHops.
+	MeanPath float64
+	// P50Path, P90Path, P99Path and MaxPath are percentiles of the number of hashes.
+	P50Path, P90Path, P99Path, MaxPath int
+	// ExpectedPath is the expected mean number of hashes, 1 + ln(a/w), for an anchor with
+	// capacity a and w working buckets. See Section IV in the paper.
+	ExpectedPath float64
+	// MeanHops is the mean number of buckets visited to assign each key, including
+	// successors followed through K, as returned by GetPath.
+	MeanHops float64
+}
+
+// Measure the distribution of a sample of keys across the working buckets of the anchor.
+func (a *Anchor) Stats(sampleKeys []uint64) *Stats {
+	s := newStats(len(a.A), int(a.N), len(sampleKeys))
+	path := make([]uint32, 0, 64)
+	for i, key := range sampleKeys {
+		path = a.GetPath(key, path[:0])
+		s.Load[path[len(path)-1]]++
+		s.pathLengths[i] = hashSteps(len(path), func(j int) uint32 { return uint32(a.A[path[j]]) })
+		s.hops += len(path)
+	}
+	return s.finish(func(b int) bool { return a.A[b] == 0 })
+}
+
+// Measure the distribution of a sample of keys across the working buckets of the anchor.
+func (a *CompactAnchor) Stats(sampleKeys []uint64) *Stats {
+	s := newStats(len(a.A), int(a.N), len(sampleKeys))
+	path := make([]uint16, 0, 64)
+	for i, key := range sampleKeys {
+		path = a.GetPath(key, path[:0])
+		s.Load[path[len(path)-1]]++
+		s.pathLengths[i] = hashSteps(len(path), func(j int) uint32 { return uint32(a.A[path[j]]) })
+		s.hops += len(path)
+	}
+	return s.finish(func(b int) bool { return a.A[b] == 0 })
+}
+
+// Measure the distribution of a sample of keys across the working buckets of the anchor.
+func (f *FrozenAnchor) Stats(sampleKeys []uint64) *Stats {
+	working := 0
+	for _, A := range f.a {
+		if A == 0 {
+			working++
+		}
+	}
+	s := newStats(len(f.a), working, len(sampleKeys))
+	path := make([]uint32, 0, 64)
+	for i, key := range sampleKeys {
+		path = f.GetPath(key, path[:0])
+		s.Load[path[len(path)-1]]++
+		s.pathLengths[i] = hashSteps(len(path), func(j int) uint32 { return f.a[path[j]] })
+		s.hops += len(path)
+	}
+	return s.finish(func(b int) bool { return f.a[b] == 0 })
+}
+
+type statsBuilder struct {
+	Stats
+	pathLengths []int
+	hops        int
+}
+
+// Count the hashes computed while following a path of length n returned by GetPath, where
+// size(i) is A[path[i]]. After each rehash from b, successors are followed until a bucket
+// b' with A[b'] < A[b] is reached.
+func hashSteps(n int, size func(i int) uint32) int {
+	steps := 1
+	for b, i := 0, 1; i < n; steps++ {
+		for size(i) >= size(b) {
+			i++
+		}
+		b, i = i, i+1
+	}
+	return steps
+}
+
+func newStats(capacity, working, keys int) *statsBuilder {
+	return &statsBuilder{
+		Stats: Stats{
+			Keys:         keys,
+			Working:      working,
+			Load:         make([]int, capacity),
+			ExpectedPath: 1 + math.Log(float64(capacity)/float64(working)),
+		},
+		pathLengths: make([]int, keys),
+	}
+}
+
+func (s *statsBuilder) finish(isWorking func(b int) bool) *Stats {
+	s.MeanLoad = float64(s.Keys) / float64(s.Working)
+	s.MinLoad, s.MaxLoad = math.MaxInt, 0
+	variance := 0.0
+	for b, load := range s.Load {
+		if !isWorking(b) {
+			continue
+		}
+		if load < s.MinLoad {
+			s.MinLoad = load
+		}
+		if load > s.MaxLoad {
+			s.MaxLoad = load
+		}
+		d := float64(load) - s.MeanLoad
+		variance += d * d
+	}
+	variance /= float64(s.Working)
+	s.StdDev = math.Sqrt(variance)
+	s.PValue = 1
+	if s.Keys > 0 {
+		s.MaxImbalance, s.MinImbalance = float64(s.MaxLoad)/s.MeanLoad, float64(s.MinLoad)/s.MeanLoad
+		s.ChiSquare = variance * float64(s.Working) / s.MeanLoad
+		if s.Working > 1 {
+			s.PValue = chiSquareSurvival(s.ChiSquare, float64(s.Working-1))
+		}
+	}
+
+	sort.Ints(s.pathLengths)
+	sum := 0
+	for _, n := range s.pathLengths {
+		sum += n
+	}
+	if s.Keys > 0 {
+		s.MeanPath = float64(sum) / float64(s.Keys)
+		s.MeanHops = float64(s.hops) / float64(s.Keys)
+		s.P50Path = s.pathLengths[(s.Keys-1)*50/100]
+		s.P90Path = s.pathLengths[(s.Keys-1)*90/100]
+		s.P99Path = s.pathLengths[(s.Keys-1)*99/100]
+		s.MaxPath = s.pathLengths[s.Keys-1]
+	}
+	return &s.Stats
+}
+
+// Get the probability that a chi-square distributed variable with df degrees of freedom
+// is at least x. This is the regularized upper incomplete gamma function Q(df/2, x/2).
+func chiSquareSurvival(x, df float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	a, x := df/2, x/2
+	lgammaA, _ := math.Lgamma(a)
+	prefix := math.Exp(a*math.Log(x) - x - lgammaA)
+	if x < a+1 {
+		// Series expansion of P(a, x)
+		sum, term := 1/a, 1/a
+		for n := 1.0; n < 1000 && term > sum*1e-15; n++ {
+			term *= x / (a + n)
+			sum += term
+		}
+		return math.Max(0, 1-prefix*sum)
+	}
+	// Continued fraction for Q(a, x) (modified Lentz's method)
+	const tiny = 1e-300
+	b := x + 1 - a
+	c, d := 1/tiny, 1/b
+	h := d
+	for i := 1.0; i < 1000; i++ {
+		an := -i * (i - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return prefix * h
+}
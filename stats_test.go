@@ -0,0 +1,131 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	const (
+		buckets = 10
+		used    = 5
+	)
+	keys := make([]uint64, 1e5)
+	for i := range keys {
+		keys[i] = uint64(i)
+	}
+	a := NewAnchor(buckets, used)
+	s := a.Stats(keys)
+	t.Logf("%+v", *s)
+
+	if s.Working != used || s.Keys != len(keys) {
+		t.Fatalf("working = %v, keys = %v", s.Working, s.Keys)
+	}
+	total := 0
+	for b, load := range s.Load {
+		if b >= used && load != 0 {
+			t.Fatalf("%v keys assigned to removed bucket %v", load, b)
+		}
+		total += load
+	}
+	if total != len(keys) {
+		t.Fatalf("total load = %v, expected %v", total, len(keys))
+	}
+	if s.PValue < 1e-4 {
+		t.Fatalf("chi-square = %v, p-value = %v", s.ChiSquare, s.PValue)
+	}
+	if math.Abs(s.MeanPath-s.ExpectedPath) > 0.1 {
+		t.Fatalf("mean path = %v, expected %v", s.MeanPath, s.ExpectedPath)
+	}
+	if s.P50Path < 1 || s.P50Path > s.P90Path || s.P90Path > s.P99Path || s.P99Path > s.MaxPath {
+		t.Fatalf("path percentiles = %v, %v, %v, %v", s.P50Path, s.P90Path, s.P99Path, s.MaxPath)
+	}
+
+	c := NewCompactAnchor(buckets, used)
+	if cs := c.Stats(keys); cs.ChiSquare != s.ChiSquare || cs.MeanPath != s.MeanPath {
+		t.Fatalf("compact stats = %+v, stats = %+v", *cs, *s)
+	}
+	if fs := a.Freeze().Stats(keys); fs.ChiSquare != s.ChiSquare || fs.MeanPath != s.MeanPath {
+		t.Fatalf("frozen stats = %+v, stats = %+v", *fs, *s)
+	}
+}
+
+func TestStatsRandomRemoval(t *testing.T) {
+	const (
+		buckets = 10000
+		removed = 9000
+	)
+	rng := rand.New(rand.NewSource(1))
+	a := NewAnchor(buckets, buckets)
+	c := NewCompactAnchor(buckets, buckets)
+	for _, b := range rng.Perm(buckets)[:removed] {
+		a.RemoveBucket(uint32(b))
+		c.RemoveBucket(uint16(b))
+	}
+	keys := make([]uint64, 1e5)
+	for i := range keys {
+		keys[i] = rng.Uint64()
+	}
+	s := a.Stats(keys)
+	t.Logf("mean path = %v, expected %v, mean hops = %v", s.MeanPath, s.ExpectedPath, s.MeanHops)
+
+	// Successors followed through K are not hashes, and would inflate the mean well beyond
+	// 1 + ln(a/w) after random removals
+	if math.Abs(s.MeanPath-s.ExpectedPath) > 0.1 {
+		t.Fatalf("mean path = %v, expected %v", s.MeanPath, s.ExpectedPath)
+	}
+	if s.MeanHops < s.MeanPath {
+		t.Fatalf("mean hops = %v, mean path = %v", s.MeanHops, s.MeanPath)
+	}
+	for _, key := range keys[:1000] {
+		path := a.GetPath(key, nil)
+		steps := hashSteps(len(path), func(j int) uint32 { return a.A[path[j]] })
+		if explained := len(a.Explain(key).Steps) + 1; steps != explained {
+			t.Fatalf("key %v: %v hashes, %v explained", key, steps, explained)
+		}
+	}
+	if cs := c.Stats(keys); cs.MeanPath != s.MeanPath || cs.MeanHops != s.MeanHops || cs.MaxPath != s.MaxPath {
+		t.Fatalf("compact stats = %+v, stats = %+v", *cs, *s)
+	}
+	if fs := a.Freeze().Stats(keys); fs.MeanPath != s.MeanPath || fs.MeanHops != s.MeanHops || fs.MaxPath != s.MaxPath {
+		t.Fatalf("frozen stats = %+v, stats = %+v", *fs, *s)
+	}
+}
+
+func TestChiSquareSurvival(t *testing.T) {
+	// Reference values from standard chi-square tables
+	for _, tc := range []struct{ x, df, p float64 }{
+		{3.841, 1, 0.05},
+		{6.635, 1, 0.01},
+		{16.919, 9, 0.05},
+		{9.342, 10, 0.50},
+		{124.342, 100, 0.05},
+	} {
+		if p := chiSquareSurvival(tc.x, tc.df); math.Abs(p-tc.p) > 1e-3 {
+			t.Fatalf("Q(%v; %v) = %v, expected %v", tc.x, tc.df, p, tc.p)
+		}
+	}
+}
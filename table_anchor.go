@@ -0,0 +1,146 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+// Compact AnchorHash implementation with a precomputed lookup table.
+//
+// GetBucket on CompactAnchor follows a chain of successors in K for each removed bucket
+// in the path of a key. A table anchor instead stores the working set Wb just after the
+// removal of each removed bucket b, so each step through a removed bucket is a single
+// array index. The table requires at most a*(a-1)/2 buckets of memory for an anchor with
+// capacity a, so it is intended for small anchors with at most a few hundred buckets.
+//
+// Every key will be assigned to exactly the same bucket as CompactAnchor.GetBucket would
+// assign it. The number of removed buckets visited for a key is unchanged (1 + ln(a/w)
+// on average, for w working buckets), but the time spent in each removed bucket no longer
+// depends on the order or number of removals. The table is updated incrementally by
+// AddBucket and RemoveBucket.
+//
+// A table anchor is not a Maglev-style table, where a lookup is a single index by
+// hash(k) mod M. Such a table can only agree with GetBucket for every key if the bucket
+// for each key depends only on hash(k) mod M, but the bucket chosen within each removed
+// bucket b depends on a further 32 bits of the key's hash (hb(k) mod |Wb|), for as many
+// removed buckets as the key visits. Lookups which begin at a working bucket are a single
+// index into A, as for CompactAnchor; the table only speeds up lookups which begin at a
+// removed bucket.
+type TableAnchor struct {
+	a CompactAnchor
+	// t holds Wb for each removed bucket b, in the order in which the buckets were removed.
+	t []uint16
+	// off[b] is the offset of Wb within t for each removed bucket b.
+	off []uint32
+}
+
+// Create a new table anchor with a given capacity and initial size.
+//
+// See NewCompactAnchor for more information. NewTableAnchor panics under the same
+// conditions as NewCompactAnchor.
+func NewTableAnchor(buckets, used int) *TableAnchor {
+	if buckets > 1<<16 || used < 1 || used > buckets {
+		panic("anchor: invalid capacity or size for a table anchor")
+	}
+	return NewCompactAnchor(buckets, used).Table()
+}
+
+// Create a table anchor from a copy of the anchor.
+//
+// Later changes to the anchor will not affect the table anchor.
+func (a *CompactAnchor) Table() *TableAnchor {
+	t := &TableAnchor{
+		a: CompactAnchor{
+			A: append([]uint16(nil), a.A...),
+			K: append([]uint16(nil), a.K...),
+			W: append([]uint16(nil), a.W...),
+			L: append([]uint16(nil), a.L...),
+			R: append(make([]uint16, 0, len(a.A)), a.R...),
+			N: a.N,
+			v: a.v,
+		},
+		t:   []uint16{},
+		off: make([]uint32, len(a.A)),
+	}
+	for _, b := range t.a.R {
+		t.push(b)
+	}
+	return t
+}
+
+// Append Wb to the table for a removed bucket b.
+//
+// Wb[h] is found by following the successors of h in K, exactly as GetBucket would.
+func (t *TableAnchor) push(b uint16) {
+	A, K := t.a.A, t.a.K
+	t.off[b] = uint32(len(t.t))
+	for h := uint16(0); h < A[b]; h++ {
+		w := h
+		for A[w] >= A[b] {
+			w = K[w]
+		}
+		t.t = append(t.t, w)
+	}
+}
+
+// Get the bucket which a hash-key is assigned to.
+//
+// See CompactAnchor.GetBucket for more information.
+//
+//	GETBUCKET(k)
+//	b ← hash(k) mod a
+//	while A[b] > 0 do          ◃ b is removed
+//	  h ← hb(k)                ◃ hb(k) ≡ hash(k) mod A[b]
+//	  b ← Wb[h]
+//	return b
+func (t *TableAnchor) GetBucket(key uint64) uint16 {
+	A, T, off := t.a.A, t.t, t.off
+	ha, hb, hc, hd := fleaInit(key)
+	b := uint16(fastMod(uint64(hd), uint64(len(A))))
+	for A[b] > 0 {
+		ha, hb, hc, hd = fleaRound(ha, hb, hc, hd)
+		b = T[off[b]+fastMod(uint64(hd), uint64(A[b]))]
+	}
+	return b
+}
+
+// Add a bucket to the anchor.
+//
+// See CompactAnchor.AddBucket for more information.
+func (t *TableAnchor) AddBucket() uint16 {
+	b := t.a.AddBucket()
+	t.t = t.t[:t.off[b]]
+	return b
+}
+
+// Remove a bucket from the anchor.
+//
+// See CompactAnchor.RemoveBucket for more information.
+func (t *TableAnchor) RemoveBucket(b uint16) {
+	v := t.a.v
+	if t.a.RemoveBucket(b); t.a.v != v {
+		t.push(b)
+	}
+}
+
+// Get the version of the anchor.
+//
+// See Anchor.Version for more information.
+func (t *TableAnchor) Version() uint64 { return t.a.v }
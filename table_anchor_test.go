@@ -0,0 +1,63 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestTableAnchor(t *testing.T) {
+	const (
+		buckets = 40
+		used    = 30
+	)
+	a := NewCompactAnchor(buckets, used)
+	ta := NewTableAnchor(buckets, used)
+	rng := rand.New(rand.NewSource(1))
+
+	check := func(step int) {
+		for k := uint64(0); k < 1e4; k++ {
+			if b, tb := a.GetBucket(k), ta.GetBucket(k); b != tb {
+				t.Fatalf("step %v: key %v: table bucket = %v, bucket = %v", step, k, tb, b)
+			}
+		}
+		if rebuilt := a.Table(); !reflect.DeepEqual(rebuilt.t, ta.t) {
+			t.Fatalf("step %v: table = %v, rebuilt table = %v", step, ta.t, rebuilt.t)
+		}
+	}
+	check(0)
+	for step := 1; step <= 200; step++ {
+		if len(a.R) > 0 && (a.N < 2 || rng.Intn(2) == 0) {
+			if b, tb := a.AddBucket(), ta.AddBucket(); b != tb {
+				t.Fatalf("step %v: table added bucket %v, anchor added bucket %v", step, tb, b)
+			}
+		} else {
+			b := uint16(rng.Intn(buckets))
+			a.RemoveBucket(b)
+			ta.RemoveBucket(b)
+		}
+		check(step)
+	}
+}
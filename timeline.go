@@ -0,0 +1,271 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"time"
+)
+
+// ErrVersionNotRetained is returned when looking up a version which a timeline has
+// never recorded or has discarded.
+var ErrVersionNotRetained = errors.New("anchor: version not retained")
+
+// Number of bits of a bucket which select a child of each node in a timeline.
+const timelineBits = 6
+
+// Persistent AnchorHash implementation which retains past versions for lookups.
+//
+// Lookups only read A and K, and each call to AddBucket or RemoveBucket changes A[b] and
+// K[b] for a single bucket b. A timeline stores A and K for each version in a
+// copy-on-write radix tree, so each new version copies only the path to the changed
+// bucket and shares all other nodes with the previous version. Each version retains
+// O(log a) additional memory, and lookups at any retained version take O(log a) time
+// for each bucket visited.
+//
+// A timeline is not safe for concurrent use.
+type Timeline struct {
+	a        *Anchor
+	versions []timelineVersion
+	// shift is the shift which selects a child of the root; leaves have a shift of 0.
+	shift uint
+	opts  TimelineOptions
+}
+
+// TimelineOptions configures the versions retained by a timeline.
+type TimelineOptions struct {
+	// MaxVersions is the maximum number of versions retained, including the current
+	// version. Zero retains any number of versions.
+	MaxVersions int
+	// MaxAge discards versions which were replaced more than MaxAge ago. The current
+	// version is always retained. Zero retains versions of any age.
+	MaxAge time.Duration
+	// Now returns the current time. Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// TimelineVersion describes a version retained by a timeline.
+type TimelineVersion struct {
+	// Version is the version of the anchor.
+	Version uint64
+	// Time is the time at which the version was created.
+	Time time.Time
+}
+
+type timelineVersion struct {
+	TimelineVersion
+	root *timelineNode
+}
+
+// A node in the radix tree of a timeline. Leaves hold A[b] and K[b] for up to
+// 1<<timelineBits buckets, and all other nodes hold up to 1<<timelineBits children.
+type timelineNode struct {
+	children []*timelineNode
+	entries  []entry32
+}
+
+// A and K for a single bucket.
+type entry32 struct{ a, k uint32 }
+
+// Create a new timeline which begins with a copy of an anchor.
+//
+// Later changes to the anchor will not affect the timeline; see Timeline.AddBucket and
+// Timeline.RemoveBucket.
+func NewTimeline(a *Anchor, opts TimelineOptions) *Timeline {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	t := &Timeline{a: a.clone(), opts: opts}
+	for len(a.A) > 1<<(t.shift+timelineBits) {
+		t.shift += timelineBits
+	}
+	root := t.build(t.shift, 0)
+	t.versions = []timelineVersion{{TimelineVersion{a.v, opts.Now()}, root}}
+	return t
+}
+
+// Build the subtree which holds the buckets beginning at lo from the current anchor.
+func (t *Timeline) build(shift uint, lo int) *timelineNode {
+	n := &timelineNode{}
+	if shift == 0 {
+		n.entries = make([]entry32, min(1<<timelineBits, len(t.a.A)-lo))
+		for i := range n.entries {
+			n.entries[i] = entry32{t.a.A[lo+i], t.a.K[lo+i]}
+		}
+		return n
+	}
+	for c := lo; c < len(t.a.A) && len(n.children) < 1<<timelineBits; c += 1 << shift {
+		n.children = append(n.children, t.build(shift-timelineBits, c))
+	}
+	return n
+}
+
+// Get A[b] and K[b] within the tree beginning at root.
+func (t *Timeline) at(root *timelineNode, b uint32) (uint32, uint32) {
+	n := root
+	for shift := t.shift; shift > 0; shift -= timelineBits {
+		n = n.children[(b>>shift)&(1<<timelineBits-1)]
+	}
+	e := n.entries[b&(1<<timelineBits-1)]
+	return e.a, e.k
+}
+
+// Copy the path from n to the leaf which holds bucket b, setting A[b] and K[b] within
+// the copied leaf.
+func (t *Timeline) set(n *timelineNode, shift uint, b uint32, e entry32) *timelineNode {
+	i := (b >> shift) & (1<<timelineBits - 1)
+	if shift == 0 {
+		c := &timelineNode{entries: slices.Clone(n.entries)}
+		c.entries[i] = e
+		return c
+	}
+	c := &timelineNode{children: slices.Clone(n.children)}
+	c.children[i] = t.set(n.children[i], shift-timelineBits, b, e)
+	return c
+}
+
+// Add a bucket to the anchor and return it, recording a new version.
+//
+// See Anchor.AddBucket for more information.
+func (t *Timeline) AddBucket() uint32 {
+	b := t.a.AddBucket()
+	t.record(b)
+	return b
+}
+
+// Remove a bucket from the anchor, recording a new version if the bucket was removed.
+//
+// See Anchor.RemoveBucket for more information.
+func (t *Timeline) RemoveBucket(b uint32) {
+	v := t.a.v
+	t.a.RemoveBucket(b)
+	if t.a.v != v {
+		t.record(b)
+	}
+}
+
+// Record a new version in which A[b] and K[b] have changed, then discard old versions.
+func (t *Timeline) record(b uint32) {
+	root := t.set(t.versions[len(t.versions)-1].root, t.shift, b, entry32{t.a.A[b], t.a.K[b]})
+	t.versions = append(t.versions, timelineVersion{TimelineVersion{t.a.v, t.opts.Now()}, root})
+	t.Prune()
+}
+
+// Discard versions according to the retention options of the timeline.
+//
+// Old versions are discarded each time a new version is recorded; Prune only needs to
+// be called to discard versions which have aged since the last change.
+func (t *Timeline) Prune() {
+	drop := 0
+	if t.opts.MaxVersions > 0 && len(t.versions) > t.opts.MaxVersions {
+		drop = len(t.versions) - t.opts.MaxVersions
+	}
+	if t.opts.MaxAge > 0 {
+		cutoff := t.opts.Now().Add(-t.opts.MaxAge)
+		for drop < len(t.versions)-1 && t.versions[drop+1].Time.Before(cutoff) {
+			drop++
+		}
+	}
+	if drop > 0 {
+		clear(t.versions[:drop])
+		t.versions = t.versions[drop:]
+	}
+}
+
+// Get the current version of the anchor.
+func (t *Timeline) Version() uint64 { return t.a.v }
+
+// Get the bucket which a hash-key is assigned to in the current version.
+//
+// See Anchor.GetBucket for more information.
+func (t *Timeline) GetBucket(key uint64) uint32 { return t.a.GetBucket(key) }
+
+// Get the bucket which a hash-key was assigned to in a retained version.
+//
+// The search is traced as by ExplainAt, so each lookup allocates; FreezeAt is cheaper for
+// many lookups in the same version. ErrVersionNotRetained will be returned if the version
+// is not retained.
+func (t *Timeline) GetBucketAt(key uint64, version uint64) (uint32, error) {
+	e, err := t.ExplainAt(key, version)
+	if err != nil {
+		return 0, err
+	}
+	return e.Bucket, nil
+}
+
+// Explain why a hash-key was assigned to its bucket in a retained version.
+//
+// ErrVersionNotRetained will be returned if the version is not retained.
+func (t *Timeline) ExplainAt(key uint64, version uint64) (*Explanation, error) {
+	root, ok := t.root(version)
+	if !ok {
+		return nil, ErrVersionNotRetained
+	}
+	return explain(key, len(t.a.A), func(b uint32) (uint32, uint32) { return t.at(root, b) }), nil
+}
+
+// Get the version which was current at a given time.
+//
+// False will be returned if the time precedes the oldest retained version.
+func (t *Timeline) VersionAt(when time.Time) (uint64, bool) {
+	i := sort.Search(len(t.versions), func(i int) bool { return t.versions[i].Time.After(when) })
+	if i == 0 {
+		return 0, false
+	}
+	return t.versions[i-1].Version, true
+}
+
+// Get the retained versions, oldest first.
+func (t *Timeline) Versions() []TimelineVersion {
+	versions := make([]TimelineVersion, len(t.versions))
+	for i, v := range t.versions {
+		versions[i] = v.TimelineVersion
+	}
+	return versions
+}
+
+// Create an immutable, lookup-only copy of the anchor as of a retained version.
+//
+// ErrVersionNotRetained will be returned if the version is not retained.
+func (t *Timeline) FreezeAt(version uint64) (*FrozenAnchor, error) {
+	root, ok := t.root(version)
+	if !ok {
+		return nil, ErrVersionNotRetained
+	}
+	f := &FrozenAnchor{a: make([]uint32, len(t.a.A)), k: make([]uint32, len(t.a.A)), v: version}
+	for b := range t.a.A {
+		f.a[b], f.k[b] = t.at(root, uint32(b))
+	}
+	return f, nil
+}
+
+// Find the root of the tree for a retained version.
+func (t *Timeline) root(version uint64) (*timelineNode, bool) {
+	i := sort.Search(len(t.versions), func(i int) bool { return t.versions[i].Version >= version })
+	if i == len(t.versions) || t.versions[i].Version != version {
+		return nil, false
+	}
+	return t.versions[i].root, true
+}
@@ -0,0 +1,134 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// A clock which advances by one minute each time it is read.
+func testClock() func() time.Time {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	for _, size := range [][2]int{{10, 8}, {64, 64}, {65, 40}, {5000, 4000}} {
+		capacity, used := size[0], size[1]
+		rng := rand.New(rand.NewSource(int64(capacity)))
+		tl := NewTimeline(NewAnchor(capacity, used), TimelineOptions{Now: testClock()})
+		a := NewAnchor(capacity, used)
+		frozen := map[uint64]*FrozenAnchor{0: a.Freeze()}
+		for i := 0; i < 100; i++ {
+			if a.Removed() > 0 && rng.Intn(3) == 0 {
+				if b, tb := a.AddBucket(), tl.AddBucket(); b != tb {
+					t.Fatalf("timeline added %v, expected %v", tb, b)
+				}
+			} else {
+				b := uint32(rng.Intn(capacity))
+				a.RemoveBucket(b)
+				tl.RemoveBucket(b)
+			}
+			frozen[a.Version()] = a.Freeze()
+		}
+		if tl.Version() != a.Version() || len(tl.Versions()) != len(frozen) {
+			t.Fatalf("capacity %v: %v versions up to %v, expected %v up to %v", capacity, len(tl.Versions()), tl.Version(), len(frozen), a.Version())
+		}
+		for v, f := range frozen {
+			for k := uint64(0); k < 200; k++ {
+				b, err := tl.GetBucketAt(k, v)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if expected := f.GetBucket(k); b != expected {
+					t.Fatalf("capacity %v, version %v, key %v: bucket = %v, expected %v", capacity, v, k, b, expected)
+				}
+			}
+			if ft, err := tl.FreezeAt(v); err != nil || !reflect.DeepEqual(ft, f) {
+				t.Fatalf("capacity %v, version %v: frozen timeline differs (err = %v)", capacity, v, err)
+			}
+		}
+		if e, err := tl.ExplainAt(12345, 0); err != nil || e.Bucket != frozen[0].GetBucket(12345) {
+			t.Fatalf("capacity %v: explanation = %v (err = %v)", capacity, e, err)
+		}
+	}
+}
+
+func TestTimelineVersionAt(t *testing.T) {
+	tl := NewTimeline(NewAnchor(10, 10), TimelineOptions{Now: testClock()})
+	tl.RemoveBucket(3)
+	tl.RemoveBucket(3) // no effect
+	tl.RemoveBucket(5)
+	versions := tl.Versions()
+	if len(versions) != 3 {
+		t.Fatalf("versions = %v, expected 3 versions", versions)
+	}
+	if _, ok := tl.VersionAt(versions[0].Time.Add(-time.Second)); ok {
+		t.Fatalf("found a version before the first version")
+	}
+	for i, v := range versions {
+		if got, ok := tl.VersionAt(v.Time.Add(time.Second)); !ok || got != v.Version {
+			t.Fatalf("version %v: version at %v = %v, expected %v", i, v.Time, got, v.Version)
+		}
+	}
+	if _, err := tl.GetBucketAt(0, 3); err != ErrVersionNotRetained {
+		t.Fatalf("err = %v, expected %v", err, ErrVersionNotRetained)
+	}
+}
+
+func TestTimelineRetention(t *testing.T) {
+	tl := NewTimeline(NewAnchor(100, 100), TimelineOptions{MaxVersions: 5, Now: testClock()})
+	for b := uint32(0); b < 10; b++ {
+		tl.RemoveBucket(b)
+	}
+	versions := tl.Versions()
+	if len(versions) != 5 || versions[0].Version != 6 || versions[4].Version != 10 {
+		t.Fatalf("versions = %v, expected versions 6 through 10", versions)
+	}
+	if _, err := tl.GetBucketAt(0, 5); err != ErrVersionNotRetained {
+		t.Fatalf("err = %v, expected %v", err, ErrVersionNotRetained)
+	}
+
+	// The clock advances by a minute for each version, and again for each Prune
+	clock := testClock()
+	tl = NewTimeline(NewAnchor(100, 100), TimelineOptions{MaxAge: 3 * time.Minute, Now: clock})
+	for b := uint32(0); b < 10; b++ {
+		tl.RemoveBucket(b)
+	}
+	versions = tl.Versions()
+	if len(versions) != 3 || versions[len(versions)-1].Version != 10 {
+		t.Fatalf("versions = %v, expected versions 8 through 10", versions)
+	}
+	for i := 0; i < 10; i++ {
+		tl.Prune()
+	}
+	if versions = tl.Versions(); len(versions) != 1 || versions[0].Version != 10 {
+		t.Fatalf("versions = %v, expected only the current version", versions)
+	}
+}
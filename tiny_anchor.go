@@ -0,0 +1,125 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+// Tiny, minimal-memory AnchorHash implementation.
+//
+// Buckets will be stored as unsigned 8-bit integers, so the maximum size of the working set
+// will be limited to 256 buckets. A, K, W, L and R share a single allocation of 5 bytes per
+// bucket, with A and K adjacent, so the entire state of an anchor with a few dozen buckets
+// fits within a couple of cache lines.
+type TinyAnchor struct {
+	// We use an integer array A of size a to represent the Anchor.
+	//
+	// Each bucket b ∈ {0, 1, ..., a−1} is represented by A[b] that either equals 0 if b
+	// is a working bucket (i.e., A[b] = 0 if b ∈ W), or else equals the size of the working
+	// set just after its removal (i.e., A[b] = |Wb| if b ∈ R).
+	A []uint8
+	// K stores the successor for each removed bucket b (i.e. the bucket that replaced it in W).
+	K []uint8
+	// W always contains the current set of working buckets in their desired order.
+	W []uint8
+	// L stores the most recent location for each bucket within W.
+	L []uint8
+	// R saves removed buckets in a LIFO order for possible future bucket additions.
+	R []uint8
+	// N is the current length of W. N is stored as an unsigned 16-bit integer, so a full
+	// working set of 256 buckets may be represented.
+	N uint16
+
+	// v is incremented by each change to the working set
+	v uint64
+}
+
+// Create a new anchor with a given capacity and initial size.
+//
+// Buckets 0 through used-1 will be working. At least one bucket must be working, and the
+// capacity may not exceed 256 buckets; NewTinyAnchor panics otherwise.
+//
+// See NewAnchor for more information.
+func NewTinyAnchor(buckets, used int) *TinyAnchor {
+	if buckets > 1<<8 || used < 1 || used > buckets {
+		panic("anchor: invalid capacity or size for a tiny anchor")
+	}
+	s := make([]uint8, 5*buckets)
+	a := &TinyAnchor{
+		A: s[0*buckets : 1*buckets : 1*buckets],
+		K: s[1*buckets : 2*buckets : 2*buckets],
+		W: s[2*buckets : 3*buckets : 3*buckets],
+		L: s[3*buckets : 4*buckets : 4*buckets],
+		R: s[4*buckets : 5*buckets-used : 5*buckets],
+		N: uint16(used),
+	}
+	for b := 0; b < buckets; b++ {
+		a.K[b], a.W[b], a.L[b] = uint8(b), uint8(b), uint8(b)
+	}
+	for b, r := buckets-1, 0; b >= used; b, r = b-1, r+1 {
+		a.A[b], a.R[r] = uint8(b), uint8(b)
+	}
+	return a
+}
+
+// Get the bucket which a hash-key is assigned to.
+//
+// See Anchor.GetBucket for more information.
+func (a *TinyAnchor) GetBucket(key uint64) uint8 {
+	return getBucket(a.A, a.K, key)
+}
+
+// Get the path to the bucket which a hash-key is assigned to.
+//
+// See Anchor.GetPath for more information.
+func (a *TinyAnchor) GetPath(key uint64, pathBuffer []uint8) []uint8 {
+	return getPath(a.A, a.K, key, pathBuffer)
+}
+
+// Add a bucket to the anchor.
+//
+// See Anchor.AddBucket for more information.
+func (a *TinyAnchor) AddBucket() uint8 {
+	if len(a.R) == 0 {
+		panic("anchor: no buckets to add")
+	}
+	b, R := addBucket(a.A, a.K, a.W, a.L, a.R, uint32(a.N))
+	a.R = R
+	a.N++
+	a.v++
+	return b
+}
+
+// Remove a bucket from the anchor.
+//
+// See Anchor.RemoveBucket for more information.
+func (a *TinyAnchor) RemoveBucket(b uint8) {
+	if int(b) >= len(a.A) || a.A[b] != 0 || a.N == 1 {
+		return
+	}
+	a.N--
+	a.R = removeBucket(a.A, a.K, a.W, a.L, a.R, b, uint32(a.N))
+	a.v++
+}
+
+// Get the version of the anchor.
+//
+// See Anchor.Version for more information.
+func (a *TinyAnchor) Version() uint64 { return a.v }
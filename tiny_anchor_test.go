@@ -0,0 +1,64 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import "testing"
+
+func TestTinyAnchorMaxCapacity(t *testing.T) {
+	const buckets = 256
+	ti := NewTinyAnchor(buckets, buckets)
+	a := NewAnchor(buckets, buckets)
+	if ti.N != buckets {
+		t.Fatalf("N = %v, expected %v", ti.N, buckets)
+	}
+	for _, b := range []uint32{255, 0, 100, 254} {
+		a.RemoveBucket(b)
+		ti.RemoveBucket(uint8(b))
+	}
+	for i := 0; i < 4; i++ {
+		for k := uint64(0); k < 1e4; k++ {
+			if b, tb := a.GetBucket(k), ti.GetBucket(k); uint32(tb) != b {
+				t.Fatalf("key %v: tiny bucket = %v, bucket = %v", k, tb, b)
+			}
+		}
+		if b, tb := a.AddBucket(), ti.AddBucket(); uint32(tb) != b {
+			t.Fatalf("tiny added bucket %v, anchor added bucket %v", tb, b)
+		}
+	}
+	if ti.N != buckets {
+		t.Fatalf("N = %v after restoring all buckets, expected %v", ti.N, buckets)
+	}
+}
+
+func TestTinyAnchorInvalidSize(t *testing.T) {
+	for _, size := range [][2]int{{1<<8 + 1, 1}, {4, 0}, {4, 5}, {0, 0}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("capacity %v with %v working buckets did not panic", size[0], size[1])
+				}
+			}()
+			NewTinyAnchor(size[0], size[1])
+		}()
+	}
+}
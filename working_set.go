@@ -0,0 +1,118 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import "errors"
+
+// ErrInvalidWorkingSet is returned when creating an anchor from a working set which is
+// empty, or which contains a repeated bucket or a bucket outside of the anchor's capacity.
+var ErrInvalidWorkingSet = errors.New("anchor: invalid working set")
+
+// Create a new anchor with a given capacity and an explicit set of working buckets.
+//
+// The anchor is created in a canonical form which depends only on the capacity and the
+// set of working buckets, not on their order within the given slice: all buckets start
+// out working, and the remaining buckets are then removed in descending order. Every
+// agent which receives the same capacity and working set will create an identical
+// anchor, so keys will be assigned to the same buckets and buckets will be added in
+// the same order. When the working set is {0, 1, ..., used−1}, the anchor will be
+// identical to the anchor created by NewAnchor(capacity, used).
+//
+//	NEWANCHORFROMWORKINGSET(a, S)
+//	INITANCHOR(a, a)
+//	for b = a−1 downto 0 do
+//	  if b ∉ S then
+//	    REMOVEBUCKET(b)
+//
+// ErrInvalidWorkingSet will be returned if the working set is empty or contains a repeated
+// or out-of-range bucket.
+func NewAnchorFromWorkingSet(capacity int, working []uint32) (*Anchor, error) {
+	if capacity <= 0 || uint64(capacity) > 1<<32-1 || len(working) == 0 {
+		return nil, ErrInvalidWorkingSet
+	}
+	isWorking := make([]bool, capacity)
+	for _, b := range working {
+		if int64(b) >= int64(capacity) || isWorking[b] {
+			return nil, ErrInvalidWorkingSet
+		}
+		isWorking[b] = true
+	}
+	a := NewAnchor(capacity, capacity)
+	for b := capacity - 1; b >= 0; b-- {
+		if !isWorking[b] {
+			a.RemoveBucket(uint32(b))
+		}
+	}
+	a.v = 0
+	return a, nil
+}
+
+// Create a new compact anchor with a given capacity and an explicit set of working buckets.
+//
+// See NewAnchorFromWorkingSet for more information. ErrTooLarge will be returned if the
+// capacity exceeds 65,536 buckets.
+func NewCompactAnchorFromWorkingSet(capacity int, working []uint16) (*CompactAnchor, error) {
+	if capacity > 1<<16 {
+		return nil, ErrTooLarge
+	}
+	if capacity <= 0 || len(working) == 0 {
+		return nil, ErrInvalidWorkingSet
+	}
+	isWorking := make([]bool, capacity)
+	for _, b := range working {
+		if int(b) >= capacity || isWorking[b] {
+			return nil, ErrInvalidWorkingSet
+		}
+		isWorking[b] = true
+	}
+	a := NewCompactAnchor(capacity, capacity)
+	for b := capacity - 1; b >= 0; b-- {
+		if !isWorking[b] {
+			a.RemoveBucket(uint16(b))
+		}
+	}
+	a.v = 0
+	return a, nil
+}
+
+// Create a new hash with a given capacity and an explicit set of working buckets.
+//
+// See NewAnchorFromWorkingSet for more information.
+func NewFromWorkingSet(capacity int, working []uint32) (*Hash, error) {
+	a, err := NewAnchorFromWorkingSet(capacity, working)
+	if err != nil {
+		return nil, err
+	}
+	return &Hash{a: *a}, nil
+}
+
+// Create a new compact hash with a given capacity and an explicit set of working buckets.
+//
+// See NewCompactAnchorFromWorkingSet for more information.
+func NewCompactFromWorkingSet(capacity int, working []uint16) (*CompactHash, error) {
+	a, err := NewCompactAnchorFromWorkingSet(capacity, working)
+	if err != nil {
+		return nil, err
+	}
+	return &CompactHash{a: *a}, nil
+}
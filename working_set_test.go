@@ -0,0 +1,117 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2019 West Damron
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package anchor
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestNewAnchorFromWorkingSet(t *testing.T) {
+	for _, size := range [][2]int{{1, 1}, {16, 1}, {16, 10}, {16, 16}, {100, 37}} {
+		capacity, used := size[0], size[1]
+		working := make([]uint32, used)
+		compactWorking := make([]uint16, used)
+		for b := range working {
+			working[b], compactWorking[b] = uint32(b), uint16(b)
+		}
+		a, err := NewAnchorFromWorkingSet(capacity, working)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := NewAnchor(capacity, used); !reflect.DeepEqual(a, expected) {
+			t.Fatalf("NewAnchorFromWorkingSet(%v, 0..%v) = %+v, expected %+v", capacity, used-1, a, expected)
+		}
+		c, err := NewCompactAnchorFromWorkingSet(capacity, compactWorking)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := NewCompactAnchor(capacity, used); !reflect.DeepEqual(c, expected) {
+			t.Fatalf("NewCompactAnchorFromWorkingSet(%v, 0..%v) = %+v, expected %+v", capacity, used-1, c, expected)
+		}
+	}
+}
+
+func TestNewAnchorFromWorkingSetCanonical(t *testing.T) {
+	working := []uint32{0, 3, 9}
+	a, err := NewAnchorFromWorkingSet(16, working)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Len() != 3 || !a.IsWorking(0) || !a.IsWorking(3) || !a.IsWorking(9) || a.Version() != 0 {
+		t.Fatalf("working = %v, version = %v, expected [0 3 9] at version 0", a.W[:a.N], a.Version())
+	}
+	if !a.valid() {
+		t.Fatalf("anchor created from a working set is invalid")
+	}
+
+	// The order of the working set must not matter
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		rng.Shuffle(len(working), func(i, j int) { working[i], working[j] = working[j], working[i] })
+		shuffled, _ := NewAnchorFromWorkingSet(16, working)
+		if !reflect.DeepEqual(shuffled, a) {
+			t.Fatalf("working set %v: anchor differs from canonical anchor", working)
+		}
+	}
+	for k := uint64(0); k < 1e4; k++ {
+		if b := a.GetBucket(k); !a.IsWorking(b) {
+			t.Fatalf("key %v assigned to removed bucket %v", k, b)
+		}
+	}
+
+	h, err := NewFromWorkingSet(16, working)
+	if err != nil || !reflect.DeepEqual(h.Anchor(), a) {
+		t.Fatalf("hash differs from canonical anchor (err = %v)", err)
+	}
+	c, err := NewCompactAnchorFromWorkingSet(16, []uint16{9, 0, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted, _ := a.ToCompact(); !reflect.DeepEqual(c, converted) {
+		t.Fatalf("compact anchor differs from canonical anchor")
+	}
+}
+
+func TestNewAnchorFromWorkingSetInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		capacity int
+		working  []uint32
+	}{
+		{16, nil},
+		{16, []uint32{1, 2, 1}},
+		{16, []uint32{16}},
+		{0, []uint32{0}},
+	} {
+		if _, err := NewAnchorFromWorkingSet(tc.capacity, tc.working); err != ErrInvalidWorkingSet {
+			t.Fatalf("NewAnchorFromWorkingSet(%v, %v): err = %v, expected %v", tc.capacity, tc.working, err, ErrInvalidWorkingSet)
+		}
+	}
+	if _, err := NewCompactAnchorFromWorkingSet(1<<16+1, []uint16{0}); err != ErrTooLarge {
+		t.Fatalf("err = %v, expected %v", err, ErrTooLarge)
+	}
+	if _, err := NewCompactAnchorFromWorkingSet(16, []uint16{3, 3}); err != ErrInvalidWorkingSet {
+		t.Fatalf("err = %v, expected %v", err, ErrInvalidWorkingSet)
+	}
+}